@@ -17,12 +17,11 @@ variable "password" {
   sensitive = true
 }
 
-# Note: This path uses raw NTLM and requires setting env var before running:
-#   $env:HYPERVAPI_V2_ALLOW_RAW_NTLM = "1"
+# Raw NTLM with explicit credentials (works on any OS; the server must accept NTLM).
 provider "hypervapiv2" {
   endpoint = var.endpoint
   auth {
-    method   = "negotiate"
+    method   = "ntlm"
     username = var.username
     password = var.password
   }
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// NetworkAdapter is a VM network adapter as reported by GET /api/v2/vms/{name}/adapters.
+type NetworkAdapter struct {
+	Name        string `json:"name"`
+	SwitchName  string `json:"switchName"`
+	MacAddress  string `json:"macAddress"`
+	DynamicMac  *bool  `json:"dynamicMacAddress"`
+	IsConnected bool   `json:"isConnected"`
+	VlanID      *int   `json:"vlanId"` // nil or 0 means untagged
+}
+
+type AddNetworkAdapterRequest struct {
+	Name        *string `json:"name,omitempty"`
+	SwitchName  *string `json:"switchName,omitempty"`
+	MacAddress  *string `json:"macAddress,omitempty"` // static MAC; omitted for a dynamic one
+	IsConnected *bool   `json:"isConnected,omitempty"`
+	VlanID      *int    `json:"vlanId,omitempty"`
+}
+
+func (c *Client) ListNetworkAdapters(ctx context.Context, vmName string) ([]NetworkAdapter, error) {
+	var out []NetworkAdapter
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters", url.PathEscape(vmName))
+	_, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if err != nil { return nil, err }
+	return out, nil
+}
+
+func (c *Client) AddNetworkAdapter(ctx context.Context, vmName string, req AddNetworkAdapterRequest) (*NetworkAdapter, error) {
+	var out NetworkAdapter
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters", url.PathEscape(vmName))
+	_, err := c.do(ctx, http.MethodPost, path, req, &out)
+	if err != nil { return nil, err }
+	return &out, nil
+}
+
+// ConnectAdapter connects the adapter to switchName, moving it if it is connected elsewhere.
+func (c *Client) ConnectAdapter(ctx context.Context, vmName, adapterName, switchName string) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters/%s:connect", url.PathEscape(vmName), url.PathEscape(adapterName))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{"switchName": switchName}, nil)
+	return err
+}
+
+func (c *Client) DisconnectAdapter(ctx context.Context, vmName, adapterName string) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters/%s:disconnect", url.PathEscape(vmName), url.PathEscape(adapterName))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{}, nil)
+	return err
+}
+
+// SetAdapterVlan sets the access VLAN; 0 makes the adapter untagged.
+func (c *Client) SetAdapterVlan(ctx context.Context, vmName, adapterName string, vlanID int) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters/%s:vlan", url.PathEscape(vmName), url.PathEscape(adapterName))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{"vlanId": vlanID}, nil)
+	return err
+}
+
+func (c *Client) DeleteAdapter(ctx context.Context, vmName, adapterName string) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/adapters/%s:delete", url.PathEscape(vmName), url.PathEscape(adapterName))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{}, nil)
+	return err
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// servicePrincipal builds the HTTP/<host> SPN for the API endpoint. Loopback addresses are mapped
+// to the machine name because Kerberos tickets are never issued for "localhost".
+func servicePrincipal(u *url.URL) string {
+	host := u.Hostname()
+	if host == "localhost" || host == "127.0.0.1" || host == "::1" {
+		if hn, err := os.Hostname(); err == nil && hn != "" {
+			host = hn
+		}
+	}
+	return "HTTP/" + host
+}
+
+// offeredSchemes returns the lower-cased auth schemes from a response's WWW-Authenticate headers.
+func offeredSchemes(resp *http.Response) []string {
+	var out []string
+	for _, v := range resp.Header.Values("Www-Authenticate") {
+		for _, part := range strings.Split(v, ",") {
+			f := strings.Fields(strings.TrimSpace(part))
+			// Skip auth-params such as realm="x" that follow a scheme
+			if len(f) == 0 || strings.Contains(f[0], "=") { continue }
+			out = append(out, strings.ToLower(f[0]))
+		}
+	}
+	return out
+}
+
+func offersScheme(resp *http.Response, scheme string) bool {
+	for _, s := range offeredSchemes(resp) {
+		if s == scheme { return true }
+	}
+	return false
+}
+
+// rewindable makes req's body replayable across authentication legs.
+func rewindable(req *http.Request) {
+	if req.Body == nil || req.GetBody != nil { return }
+	b, _ := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	req.ContentLength = int64(len(b))
+}
+
+// retryRequest clones req with a fresh body for another authentication leg.
+func retryRequest(req *http.Request) (*http.Request, error) {
+	r2 := req.Clone(req.Context())
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil { return nil, err }
+		r2.Body = rc
+	}
+	return r2, nil
+}
+
+// drain discards and closes a response body so the connection can be reused for the next leg.
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+}
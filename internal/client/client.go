@@ -9,12 +9,10 @@ import (
 	"net/http"
 	"net/url"
 	"time"
-	"os"
 )
 
 import (
 	"github.com/hashicorp/terraform-plugin-log/tflog"
-    ntlm "github.com/vadimi/go-http-ntlm/v2"
 )
 
 type Config struct {
@@ -24,8 +22,12 @@ type Config struct {
 	EnforcePolicyPaths bool
 	Strict             bool
 	Auth               AuthConfig
+	TLS                *TLSConfig
 	Defaults           *Defaults
 	LogHTTP            bool
+	// MaxRetries caps re-attempts of idempotent requests; nil selects the default (3), 0 disables retries.
+	MaxRetries          *int
+	RetryMaxWaitSeconds int
 }
 
 type Defaults struct {
@@ -35,25 +37,31 @@ type Defaults struct {
 }
 
 type AuthConfig struct {
-	Method   string // none | bearer | negotiate
+	Method   string // none | bearer | negotiate | ntlm
 	Username string
 	Password string
+	// Kerberos settings used by negotiate on non-Windows platforms.
+	Realm    string
+	Krb5Conf string
+	Keytab   string
+	CCache   string
+	// Bearer token sources; exactly one of Token, TokenFile, TokenEnv or TokenURL is used.
+	Token        string
+	TokenFile    string
+	TokenEnv     string
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
 }
 
 type Client struct {
 	cfg    Config
 	inner  *http.Client
 	base   *url.URL
-	bearer string
+	tokens  *cachingTokenSource
 	logHTTP bool
-}
-
-// ApiError represents the server's standard JSON error envelope.
-type ApiError struct {
-	Code      string `json:"code"`
-	Message   string `json:"message"`
-	RequestID string `json:"requestId"`
-	Details   any    `json:"details"`
+	retry   retryPolicy
 }
 
 func New(cfg Config) (*Client, error) {
@@ -72,34 +80,59 @@ func New(cfg Config) (*Client, error) {
 		}
 		tr.Proxy = http.ProxyURL(proxyURL)
 	}
+	tlsCfg, err := buildTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	tr.TLSClientConfig = tlsCfg
 
 	var rt http.RoundTripper = tr
-	// Enable Windows Integrated Auth (Negotiate)
-	if cfg.Auth.Method == "negotiate" {
-		if cfg.Auth.Username != "" {
-			// Prefer Windows impersonation + SSPI Negotiate when explicit credentials are provided.
-			// Optional raw NTLM fallback can be enabled via env var if the server accepts it.
-			if os.Getenv("HYPERVAPI_V2_ALLOW_RAW_NTLM") == "1" {
-				user := cfg.Auth.Username
-				domain := ""
-				if i := indexOfDomainSep(user); i > 0 {
-					domain = user[:i]
-					user = user[i+1:]
-				}
-				rt = &ntlm.NtlmTransport{Domain: domain, User: user, Password: cfg.Auth.Password, RoundTripper: rt}
-			} else {
-				rt = wrapNegotiateTransportWithImpersonation(tr, cfg.Auth.Username, cfg.Auth.Password)
-			}
-		} else {
-			// Use Windows SSPI for current user credentials (Kerberos/NTLM)
-			rt = wrapNegotiateTransport(rt)
+	switch cfg.Auth.Method {
+	case "", "none", "bearer":
+	case "negotiate":
+		// Integrated auth: SSPI on Windows, pure-Go Kerberos elsewhere
+		nrt, err := newNegotiateTransport(tr, cfg.Auth)
+		if err != nil {
+			return nil, err
 		}
+		rt = nrt
+	case "ntlm":
+		if cfg.Auth.Username == "" {
+			return nil, fmt.Errorf("auth.username is required for auth.method = \"ntlm\"")
+		}
+		rt = newNTLMTransport(tr, cfg.Auth.Username, cfg.Auth.Password)
+	default:
+		return nil, fmt.Errorf("unsupported auth.method %q (expected none | bearer | negotiate | ntlm)", cfg.Auth.Method)
+	}
+
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	cli := &http.Client{Timeout: timeout, Transport: rt}
+	c := &Client{cfg: cfg, inner: cli, base: u, logHTTP: cfg.LogHTTP, retry: newRetryPolicy(cfg)}
+	if cfg.Auth.Method == "bearer" {
+		// The token endpoint is usually another host: it shares the CA pool and proxy, never the
+		// API's server name, pins, client certificate or skipped verification
+		ttr := &http.Transport{Proxy: tr.Proxy, TLSClientConfig: tokenTLSConfig(tlsCfg)}
+		ts, err := newTokenSource(cfg.Auth, &http.Client{Timeout: timeout, Transport: ttr})
+		if err != nil {
+			return nil, err
+		}
+		c.tokens = ts
 	}
+	return c, nil
+}
 
-	cli := &http.Client{Timeout: time.Duration(cfg.TimeoutSeconds) * time.Second, Transport: rt}
-	return &Client{cfg: cfg, inner: cli, base: u, logHTTP: cfg.LogHTTP}, nil
+// Defaults returns the provider defaults block; zero values mean unset.
+func (c *Client) Defaults() Defaults {
+	if c.cfg.Defaults == nil { return Defaults{} }
+	return *c.cfg.Defaults
 }
 
+// Strict reports whether policy warnings must be escalated to errors (provider strict = true).
+func (c *Client) Strict() bool { return c.cfg.Strict }
+
+// EnforcePolicyPaths reports whether explicit paths are validated against policy at plan time.
+func (c *Client) EnforcePolicyPaths() bool { return c.cfg.EnforcePolicyPaths }
+
 // indexOfDomainSep finds the last backslash in DOMAIN\user
 func indexOfDomainSep(s string) int {
     for i := len(s) - 1; i >= 0; i-- {
@@ -108,83 +141,132 @@ func indexOfDomainSep(s string) int {
     return -1
 }
 
-// do performs a JSON request and unmarshals the response.
+// do performs a JSON request and unmarshals the response. Idempotent requests (see isIdempotent)
+// are retried with backoff on transport errors and transient statuses.
 func (c *Client) do(ctx context.Context, method, path string, in, out any) (*http.Response, error) {
-	var body *bytes.Reader
+	var payload []byte
 	if in != nil {
 		b, err := json.Marshal(in)
 		if err != nil {
 			return nil, err
 		}
-		body = bytes.NewReader(b)
-	} else {
-		body = bytes.NewReader(nil)
+		payload = b
 	}
 
-	fullURL := c.base.ResolveReference(&url.URL{Path: path}).String()
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	// path is already escaped and may carry a query (StopVm); url.URL{Path: path} would escape both again
+	ref, err := url.Parse(path)
 	if err != nil {
 		return nil, err
 	}
+	fullURL := c.base.ResolveReference(ref).String()
+	maxRetries := 0
+	if isIdempotent(method, path) { maxRetries = c.retry.maxRetries }
+
+	var resp *http.Response
+	var data []byte
+	retries, reauthed := 0, false
+	for {
+		var err error
+		resp, data, err = c.send(ctx, method, fullURL, payload, retries)
+		if err != nil {
+			if retries < maxRetries && retryableErr(ctx, err) {
+				retries++
+				if werr := c.waitRetry(ctx, method, fullURL, retries, nil, err.Error()); werr != nil { return nil, err }
+				continue
+			}
+			return nil, err
+		}
+		// A 401 with a cached bearer token usually means it was revoked or rotated: fetch once more
+		if resp.StatusCode == http.StatusUnauthorized && c.tokens != nil && !reauthed {
+			reauthed = true
+			c.tokens.Invalidate()
+			tflog.Debug(ctx, "auth.token_refresh", map[string]any{"url": fullURL, "reason": "401"})
+			continue
+		}
+		if retries < maxRetries && retryableStatus(resp.StatusCode) {
+			retries++
+			if werr := c.waitRetry(ctx, method, fullURL, retries, resp, http.StatusText(resp.StatusCode)); werr != nil { break }
+			continue
+		}
+		break
+	}
+	if resp.StatusCode >= 400 {
+		ae := &ApiError{Status: resp.StatusCode, Method: method, Path: path, Retries: retries}
+		// Try to decode standard JSON envelope
+		if err := json.Unmarshal(data, ae); err == nil && (ae.Message != "" || ae.Code != "") {
+			// Log structured error + compact details if present
+			fields := map[string]any{"status": resp.StatusCode, "url": fullURL, "code": ae.Code, "requestId": ae.RequestID}
+			if m, ok := ae.Details.(map[string]any); ok {
+				if s, ok := m["script"].(string); ok && s != "" { ae.Script = s; fields["ps_script"] = truncate(s, 400) }
+				if pe, ok := m["stderr"].(string); ok && pe != "" { ae.Stderr = pe; fields["ps_error"] = truncate(pe, 600) }
+			}
+			if c.logHTTP { tflog.Error(ctx, "api.error", fields) }
+			return resp, ae
+		}
+		// Fallback: include raw body snippet
+		ae.Code, ae.Message, ae.RequestID, ae.Details = "", "", "", nil
+		ae.Body = truncate(string(data), 1024)
+		if c.logHTTP { tflog.Error(ctx, "api.error", map[string]any{"status": resp.StatusCode, "url": fullURL}) }
+		return resp, ae
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// send performs a single HTTP attempt and returns the fully read response body.
+func (c *Client) send(ctx context.Context, method, fullURL string, payload []byte, attempt int) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
-	if c.cfg.Auth.Method == "bearer" && c.bearer != "" {
-		req.Header.Set("Authorization", "Bearer "+c.bearer)
+	if c.tokens != nil {
+		tok, err := c.tokens.Token(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+tok)
 	}
-	// Negotiate support would require platform-specific handlers. Stubbed here.
 
 	if c.logHTTP {
 		tflog.Debug(ctx, "http.request", map[string]any{
-			"method": method,
-			"url":    fullURL,
-			"auth":   c.cfg.Auth.Method,
+			"method":  method,
+			"url":     fullURL,
+			"auth":    c.cfg.Auth.Method,
+			"attempt": attempt + 1,
 		})
 	}
 
 	resp, err := c.inner.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 	// Read body once so we can include informative details on errors
 	data, _ := io.ReadAll(resp.Body)
 	if c.logHTTP {
 		hdr := resp.Header.Get("Www-Authenticate")
-		// Truncate response body to avoid huge logs
-		snippet := string(data)
-		if len(snippet) > 2000 { snippet = snippet[:2000] + "...(truncated)" }
-		fields := map[string]any{"status": resp.StatusCode, "url": fullURL}
+		fields := map[string]any{"status": resp.StatusCode, "url": fullURL, "retries": attempt}
 		if hdr != "" { fields["www_authenticate"] = hdr }
-		fields["body"] = snippet
+		// Truncate response body to avoid huge logs
+		fields["body"] = truncate(string(data), 2000)
 		tflog.Debug(ctx, "http.response", fields)
 	}
-	if resp.StatusCode >= 400 {
-		// Try to decode standard JSON envelope
-		var ae ApiError
-		if err := json.Unmarshal(data, &ae); err == nil && (ae.Message != "" || ae.Code != "") {
-			// Log structured error + compact details if present
-			fields := map[string]any{"status": resp.StatusCode, "url": fullURL, "code": ae.Code, "requestId": ae.RequestID}
-			if m, ok := ae.Details.(map[string]any); ok {
-				if s, ok := m["script"].(string); ok && s != "" { fields["ps_script"] = truncate(s, 400) }
-				if pe, ok := m["stderr"].(string); ok && pe != "" { fields["ps_error"] = truncate(pe, 600) }
-			}
-			if c.logHTTP { tflog.Error(ctx, "api.error", fields) }
-			// Return formatted error with code and requestId
-			msg := ae.Message
-			if msg == "" { msg = http.StatusText(resp.StatusCode) }
-			return resp, fmt.Errorf("%s (code=%s, requestId=%s)", msg, ae.Code, ae.RequestID)
-		}
-		// Fallback: include raw body snippet
-		snippet := string(data)
-		if len(snippet) > 1024 { snippet = snippet[:1024] + "...(truncated)" }
-		if c.logHTTP { tflog.Error(ctx, "api.error", map[string]any{"status": resp.StatusCode, "url": fullURL}) }
-		return resp, fmt.Errorf("api %s %s -> %d | body=%s", method, path, resp.StatusCode, snippet)
-	}
-	if out != nil {
-		if err := json.Unmarshal(data, out); err != nil {
-			return resp, err
-		}
-	}
-	return resp, nil
+	return resp, data, nil
+}
+
+// waitRetry logs the upcoming retry and sleeps for the policy backoff. It returns ctx.Err() if the
+// caller gives up while waiting.
+func (c *Client) waitRetry(ctx context.Context, method, fullURL string, retry int, resp *http.Response, reason string) error {
+	d := c.retry.backoff(retry, resp)
+	fields := map[string]any{"method": method, "url": fullURL, "retry": retry, "max_retries": c.retry.maxRetries, "delay_ms": d.Milliseconds(), "reason": reason}
+	if resp != nil { fields["status"] = resp.StatusCode }
+	tflog.Warn(ctx, "http.retry", fields)
+	return sleepCtx(ctx, d)
 }
 
 // truncate returns a string limited to max characters with a suffix if truncated.
@@ -286,22 +368,6 @@ func (c *Client) SetFirstBootToPrimaryDisk(ctx context.Context, name string) err
     return err
 }
 
-func (c *Client) GetFirmware(ctx context.Context, name string) (map[string]any, error) {
-    var out map[string]any
-    path := fmt.Sprintf("/api/v2/vms/%s/firmware", url.PathEscape(name))
-    _, err := c.do(ctx, http.MethodGet, path, nil, &out)
-    if err != nil { return nil, err }
-    return out, nil
-}
-
-func (c *Client) GetSecurity(ctx context.Context, name string) (map[string]any, error) {
-    var out map[string]any
-    path := fmt.Sprintf("/api/v2/vms/%s/security", url.PathEscape(name))
-    _, err := c.do(ctx, http.MethodGet, path, nil, &out)
-    if err != nil { return nil, err }
-    return out, nil
-}
-
 type PolicyEffective struct {
 	Roots      []string          `json:"roots"`
 	Extensions []string          `json:"extensions"`
@@ -356,7 +422,7 @@ func (c *Client) CreateVm(ctx context.Context, req CreateVmRequest) (*CreateVmRe
 	return &out, nil
 }
 
-// ---- VM config (read-only) ----
+// ---- VM config ----
 type VmProcessorConfig struct {
 	Count int `json:"count"`
 }
@@ -366,6 +432,8 @@ type VmMemoryConfig struct {
 	Dynamic   bool  `json:"dynamic"`
 	MinMB     *int  `json:"minMB"`
 	MaxMB     *int  `json:"maxMB"`
+	BufferPercent *int `json:"bufferPercent"`
+	Priority      *int `json:"priority"` // memory weight, 0-100
 }
 
 func (c *Client) GetVmProcessorConfig(ctx context.Context, name string) (*VmProcessorConfig, error) {
@@ -384,6 +452,31 @@ func (c *Client) GetVmMemoryConfig(ctx context.Context, name string) (*VmMemoryC
 	return &out, nil
 }
 
+// SetVmProcessorCount changes the vCPU count. Hyper-V only accepts this while the VM is off.
+func (c *Client) SetVmProcessorCount(ctx context.Context, name string, count int) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/processor/config", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPut, path, map[string]any{"count": count}, nil)
+	return err
+}
+
+// SetVmMemoryRequest changes memory settings; nil fields are left as they are.
+type SetVmMemoryRequest struct {
+	StartupMB *int  `json:"startupMB,omitempty"`
+	Dynamic   *bool `json:"dynamic,omitempty"`
+	MinMB     *int  `json:"minMB,omitempty"`
+	MaxMB     *int  `json:"maxMB,omitempty"`
+	BufferPercent *int `json:"bufferPercent,omitempty"`
+	Priority      *int `json:"priority,omitempty"`
+}
+
+// SetVmMemory changes startup and dynamic memory. Running VMs accept a static-memory resize on
+// recent hosts; toggling dynamic memory needs the VM off.
+func (c *Client) SetVmMemory(ctx context.Context, name string, req SetVmMemoryRequest) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/memory/config", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPut, path, req, nil)
+	return err
+}
+
 // ---- Disks: clone (two-step, async) and attach ----
 
 type ClonePrepareRequest struct {
@@ -435,9 +528,50 @@ func (c *Client) GetCloneTask(ctx context.Context, id string) (*CloneTask, error
     return &out, nil
 }
 
+// CancelCloneTask asks the server to abort a queued or running clone.
+func (c *Client) CancelCloneTask(ctx context.Context, id string) error {
+    path := fmt.Sprintf("/api/v2/disks/clone/tasks/%s:cancel", url.PathEscape(id))
+    _, err := c.do(ctx, http.MethodPost, path, map[string]any{}, nil)
+    return err
+}
+
+// WaitCloneTask polls a clone task until it completes, logging copy progress. If ctx ends first
+// the server task is canceled so it does not keep copying in the background.
+func (c *Client) WaitCloneTask(ctx context.Context, id string) (*CloneTask, error) {
+    var last *CloneTask
+    w := TaskWaiter{
+        Name: "clone " + id,
+        Poll: func(ctx context.Context) (*TaskStatus, error) {
+            t, err := c.GetCloneTask(ctx, id)
+            if err != nil { return nil, err }
+            last = t
+            st := &TaskStatus{State: NormalizeTaskState(t.Status), Status: t.Status, Done: t.BytesCopied, Total: t.BytesTotal}
+            if t.Error != nil { st.Error = *t.Error }
+            return st, nil
+        },
+        Cancel:      func(ctx context.Context) error { return c.CancelCloneTask(ctx, id) },
+        MinInterval: time.Second,
+        MaxInterval: 10 * time.Second,
+    }
+    _, err := w.Wait(ctx)
+    return last, err
+}
+
+// DiskSlot places a disk on a controller; nil fields are chosen by the server.
+type DiskSlot struct {
+    ControllerType     string `json:"controllerType,omitempty"` // SCSI | IDE
+    ControllerNumber   *int   `json:"controllerNumber,omitempty"`
+    ControllerLocation *int   `json:"controllerLocation,omitempty"`
+}
+
 // Attach existing disk to a VM
-func (c *Client) AttachDisk(ctx context.Context, vmName string, attachPath string, readOnly bool, vhdSizeGB *int, vhdType *string, parentPath *string) error {
+func (c *Client) AttachDisk(ctx context.Context, vmName string, attachPath string, readOnly bool, vhdSizeGB *int, vhdType *string, parentPath *string, slot *DiskSlot) error {
     body := map[string]any{"attachPath": attachPath, "readOnly": readOnly}
+    if slot != nil {
+        if slot.ControllerType != "" { body["controllerType"] = slot.ControllerType }
+        if slot.ControllerNumber != nil { body["controllerNumber"] = *slot.ControllerNumber }
+        if slot.ControllerLocation != nil { body["controllerLocation"] = *slot.ControllerLocation }
+    }
     if vhdSizeGB != nil {
         body["newVhdSizeGB"] = *vhdSizeGB
     }
@@ -452,6 +586,31 @@ func (c *Client) AttachDisk(ctx context.Context, vmName string, attachPath strin
     return err
 }
 
+// DetachDisk disconnects the disk file at diskPath from the VM; the file is kept.
+func (c *Client) DetachDisk(ctx context.Context, vmName, diskPath string) error {
+    path := fmt.Sprintf("/api/v2/vms/%s/disks:detach", url.PathEscape(vmName))
+    _, err := c.do(ctx, http.MethodPost, path, map[string]any{"path": diskPath}, nil)
+    return err
+}
+
+// ResizeDisk grows a virtual disk to sizeGB. The server refuses to shrink.
+func (c *Client) ResizeDisk(ctx context.Context, diskPath string, sizeGB int) error {
+    _, err := c.do(ctx, http.MethodPost, "/api/v2/disks:resize", map[string]any{"path": diskPath, "sizeGB": sizeGB}, nil)
+    return err
+}
+
+// ConvertDisk changes a virtual disk between Dynamic and Fixed in place.
+func (c *Client) ConvertDisk(ctx context.Context, diskPath, vhdType string) error {
+    _, err := c.do(ctx, http.MethodPost, "/api/v2/disks:convert", map[string]any{"path": diskPath, "vhdType": vhdType}, nil)
+    return err
+}
+
+// DeleteDisk removes a detached disk file. Policy decides which roots are deletable.
+func (c *Client) DeleteDisk(ctx context.Context, diskPath string) error {
+    _, err := c.do(ctx, http.MethodPost, "/api/v2/disks:delete", map[string]any{"path": diskPath}, nil)
+    return err
+}
+
 // Delete VM
 type DeleteVmRequest struct {
 	Token       string `json:"token,omitempty"`
@@ -479,20 +638,6 @@ func (c *Client) DeleteVm(ctx context.Context, name string, req DeleteVmRequest)
 	return out, nil
 }
 
-// Get VM minimal view (status code returned to help Read callers handle 404)
-func (c *Client) GetVm(ctx context.Context, name string) (map[string]any, int, error) {
-	var out map[string]any
-	resp, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/vms/%s", url.PathEscape(name)), nil, &out)
-	if err != nil {
-		// When server returns error, do() wraps it; attempt a best-effort status extraction via second probe without parsing
-		if resp != nil {
-			return nil, resp.StatusCode, err
-		}
-		return nil, 0, err
-	}
-	return out, 200, nil
-}
-
 // Power operations
 func (c *Client) StartVm(ctx context.Context, name string) error {
     path := fmt.Sprintf("/api/v2/vms/%s:start", url.PathEscape(name))
@@ -502,14 +647,32 @@ func (c *Client) StartVm(ctx context.Context, name string) error {
 
 // StopVm allows graceful, force, or turnOff semantics
 func (c *Client) StopVm(ctx context.Context, name string, force bool, turnOff bool) error {
-    u := &url.URL{Path: fmt.Sprintf("/api/v2/vms/%s:stop", url.PathEscape(name))}
+    path := fmt.Sprintf("/api/v2/vms/%s:stop", url.PathEscape(name))
     q := url.Values{}
     if turnOff {
         q.Set("turnOff", "true")
     } else if force {
         q.Set("force", "true")
     }
-    u.RawQuery = q.Encode()
-    _, err := c.do(ctx, http.MethodPost, u.String(), map[string]any{}, nil)
+    if len(q) > 0 { path += "?" + q.Encode() }
+    _, err := c.do(ctx, http.MethodPost, path, map[string]any{}, nil)
+    return err
+}
+
+// SaveVm saves the VM's memory to disk and turns it off; starting it resumes from the saved state.
+func (c *Client) SaveVm(ctx context.Context, name string) error {
+    _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/vms/%s:save", url.PathEscape(name)), nil, nil)
+    return err
+}
+
+// PauseVm suspends the running VM in memory.
+func (c *Client) PauseVm(ctx context.Context, name string) error {
+    _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/vms/%s:pause", url.PathEscape(name)), nil, nil)
+    return err
+}
+
+// ResumeVm continues a paused VM.
+func (c *Client) ResumeVm(ctx context.Context, name string) error {
+    _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/v2/vms/%s:resume", url.PathEscape(name)), nil, nil)
     return err
 }
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPowerPathsEscapeNameOnce(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.URL.RequestURI())
+		_, _ = w.Write([]byte("{}"))
+	}))
+	defer ts.Close()
+	c, err := New(Config{Endpoint: ts.URL})
+	if err != nil { t.Fatal(err) }
+
+	ctx := context.Background()
+	calls := []func() error{
+		func() error { return c.StopVm(ctx, "web 01", false, false) },
+		func() error { return c.StopVm(ctx, "web 01", true, false) },
+		func() error { return c.StopVm(ctx, "web 01", false, true) },
+		func() error { return c.StartVm(ctx, "web 01") },
+		func() error { return c.SaveVm(ctx, "web 01") },
+	}
+	for _, call := range calls {
+		if err := call(); err != nil { t.Fatal(err) }
+	}
+	want := []string{
+		"/api/v2/vms/web%2001:stop",
+		"/api/v2/vms/web%2001:stop?force=true",
+		"/api/v2/vms/web%2001:stop?turnOff=true",
+		"/api/v2/vms/web%2001:start",
+		"/api/v2/vms/web%2001:save",
+	}
+	for i := range want {
+		if i >= len(got) || got[i] != want[i] { t.Fatalf("request URIs = %q, want %q", got, want) }
+	}
+}
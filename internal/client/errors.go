@@ -0,0 +1,127 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ApiError represents the server's standard JSON error envelope, enriched with the
+// HTTP status and request details so callers can branch on the failure kind instead
+// of matching message text (which may be localized by PowerShell on the host).
+type ApiError struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"requestId"`
+	Details   any    `json:"details"`
+
+	Status int    `json:"-"`
+	Method string `json:"-"`
+	Path   string `json:"-"`
+	// Script and Stderr are lifted from details when the server reports a failed PowerShell invocation.
+	Script string `json:"-"`
+	Stderr string `json:"-"`
+	// Retries is the number of re-attempts made before this error was returned.
+	Retries int `json:"-"`
+	// Body holds a truncated raw response body when the server did not return the JSON envelope.
+	Body string `json:"-"`
+}
+
+func (e *ApiError) Error() string {
+	if e.Message == "" && e.Code == "" {
+		return fmt.Sprintf("api %s %s -> %d | body=%s", e.Method, e.Path, e.Status, e.Body)
+	}
+	msg := e.Message
+	if msg == "" { msg = http.StatusText(e.Status) }
+	return fmt.Sprintf("%s (code=%s, requestId=%s)", msg, e.Code, e.RequestID)
+}
+
+// category returns the PowerShell error category reported in details, if any.
+func (e *ApiError) category() string {
+	if m, ok := e.Details.(map[string]any); ok {
+		for _, k := range []string{"category", "errorCategory"} {
+			if s, ok := m[k].(string); ok { return s }
+		}
+	}
+	return ""
+}
+
+func (e *ApiError) codeIs(names ...string) bool {
+	c := strings.ToLower(e.Code)
+	for _, n := range names {
+		if c == strings.ToLower(n) { return true }
+	}
+	return false
+}
+
+// AsApiError unwraps err to an *ApiError when the failure came from the server.
+func AsApiError(err error) (*ApiError, bool) {
+	var ae *ApiError
+	if errors.As(err, &ae) { return ae, true }
+	return nil, false
+}
+
+// IsNotFound reports whether err means the target object does not exist on the host.
+func IsNotFound(err error) bool {
+	ae, ok := AsApiError(err)
+	if !ok { return false }
+	if ae.Status == http.StatusNotFound { return true }
+	if ae.codeIs("NotFound", "VmNotFound", "ObjectNotFound", "DiskNotFound", "TaskNotFound") { return true }
+	return strings.EqualFold(ae.category(), "ObjectNotFound")
+}
+
+// IsPolicyDenied reports whether err is a policy, JEA or RBAC denial.
+func IsPolicyDenied(err error) bool {
+	ae, ok := AsApiError(err)
+	if !ok { return false }
+	if ae.Status == http.StatusForbidden { return true }
+	return ae.codeIs("PolicyDenied", "Forbidden", "PathNotAllowed", "NameNotAllowed")
+}
+
+// IsConflict reports whether err is a conflict, e.g. the VM is busy or already exists.
+func IsConflict(err error) bool {
+	ae, ok := AsApiError(err)
+	if !ok { return false }
+	if ae.Status == http.StatusConflict { return true }
+	return ae.codeIs("Conflict", "AlreadyExists", "VmBusy", "InvalidState")
+}
+
+// IsUnsupported reports whether the host cannot make the requested change at all, e.g. no vTPM on
+// a Generation 1 VM or a host without a key protector source.
+func IsUnsupported(err error) bool {
+	ae, ok := AsApiError(err)
+	if !ok { return false }
+	if ae.Status == http.StatusNotImplemented { return true }
+	if ae.codeIs("NotSupported", "Unsupported", "FeatureNotSupported", "HostNotSupported") { return true }
+	return strings.EqualFold(ae.category(), "NotImplemented")
+}
+
+// IsUnauthorized reports whether err is an authentication failure.
+func IsUnauthorized(err error) bool {
+	ae, ok := AsApiError(err)
+	if !ok { return false }
+	return ae.Status == http.StatusUnauthorized || ae.codeIs("Unauthorized")
+}
+
+// Detail renders err for a diagnostic, appending a remediation hint for well-known API failures.
+func Detail(err error) string {
+	if err == nil { return "" }
+	hint := ""
+	switch {
+	case IsUnauthorized(err):
+		hint = "Check the provider auth block and credentials."
+	case IsPolicyDenied(err):
+		hint = "The request was denied by host policy (allowed roots, extensions, name patterns or RBAC)."
+	case IsConflict(err):
+		hint = "The VM is busy or in a conflicting state; consider stop_method or wait_timeout_seconds."
+	case IsUnsupported(err):
+		hint = "The host does not support this setting for the VM."
+	}
+	if ae, ok := AsApiError(err); ok {
+		if ae.Stderr != "" { hint = strings.TrimSpace(hint + "\nHost error: " + truncate(ae.Stderr, 600)) }
+		if ae.Retries > 0 { hint = strings.TrimSpace(hint + fmt.Sprintf("\nGave up after %d retries.", ae.Retries)) }
+	}
+	if hint == "" { return err.Error() }
+	return err.Error() + "\n\n" + hint
+}
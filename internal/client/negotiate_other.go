@@ -2,13 +2,141 @@
 
 package client
 
-import "net/http"
-
-// wrapNegotiateTransport is a stub for non-Windows platforms.
-// Since Hyper-V is Windows-only, this provider won't work on other platforms,
-// but we provide a stub to allow cross-compilation.
-func wrapNegotiateTransport(base http.RoundTripper) http.RoundTripper {
-	// On non-Windows platforms, just return the base transport
-	// Negotiate authentication is not supported
-	return base
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// newNegotiateTransport performs HTTP Negotiate with Kerberos (SPNEGO) in pure Go, so Linux and
+// macOS runners authenticate the same way Windows SSPI does. Credentials are taken from, in order:
+// auth.keytab (with auth.username), auth.username + auth.password, or the credential cache named by
+// auth.ccache / KRB5CCNAME as populated by kinit.
+func newNegotiateTransport(base *http.Transport, auth AuthConfig) (http.RoundTripper, error) {
+	cl, err := newKerberosClient(auth)
+	if err != nil {
+		return nil, fmt.Errorf("negotiate: %w", err)
+	}
+	return newKerberosTransport(base, cl), nil
+}
+
+// newKerberosTransport wraps base with SPNEGO using an already configured Kerberos client. It is
+// split from newNegotiateTransport so a client bound to a stand-in KDC can be injected.
+func newKerberosTransport(base http.RoundTripper, cl *krb5client.Client) http.RoundTripper {
+	return &krbNegTransport{base: base, cl: cl}
+}
+
+func loadKrb5Config(path string) (*config.Config, error) {
+	if path == "" { path = os.Getenv("KRB5_CONFIG") }
+	if path == "" { path = "/etc/krb5.conf" }
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("load krb5 config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func newKerberosClient(auth AuthConfig) (*krb5client.Client, error) {
+	cfg, err := loadKrb5Config(auth.Krb5Conf)
+	if err != nil { return nil, err }
+	// Active Directory KDCs do not implement FAST; requesting it only adds a failed round trip
+	noFast := krb5client.DisablePAFXFAST(true)
+	switch {
+	case auth.Keytab != "":
+		user, realm := splitPrincipal(auth.Username, auth.Realm, cfg)
+		if user == "" {
+			return nil, fmt.Errorf("auth.username is required with auth.keytab")
+		}
+		kt, err := keytab.Load(auth.Keytab)
+		if err != nil {
+			return nil, fmt.Errorf("load keytab %s: %w", auth.Keytab, err)
+		}
+		return krb5client.NewWithKeytab(user, realm, kt, cfg, noFast), nil
+	case auth.Username != "":
+		user, realm := splitPrincipal(auth.Username, auth.Realm, cfg)
+		return krb5client.NewWithPassword(user, realm, auth.Password, cfg, noFast), nil
+	default:
+		path, err := ccachePath(auth.CCache)
+		if err != nil { return nil, err }
+		cc, err := credentials.LoadCCache(path)
+		if err != nil {
+			return nil, fmt.Errorf("load credential cache %s (run kinit or set auth.keytab): %w", path, err)
+		}
+		cl, err := krb5client.NewFromCCache(cc, cfg, noFast)
+		if err != nil {
+			return nil, fmt.Errorf("credential cache %s: %w", path, err)
+		}
+		return cl, nil
+	}
+}
+
+// ccachePath resolves the file credential cache; gokrb5 cannot read KEYRING: or KCM: caches.
+func ccachePath(explicit string) (string, error) {
+	p := explicit
+	if p == "" { p = os.Getenv("KRB5CCNAME") }
+	if p == "" { p = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid()) }
+	if i := strings.Index(p, ":"); i > 0 {
+		kind := strings.ToUpper(p[:i])
+		if kind != "FILE" {
+			return "", fmt.Errorf("credential cache type %s is not supported; point KRB5CCNAME at a FILE: cache", kind)
+		}
+		p = p[i+1:]
+	}
+	return p, nil
+}
+
+// splitPrincipal accepts user@REALM, DOMAIN\user or a bare user name. Without an explicit realm the
+// krb5.conf default_realm is preferred over the NetBIOS domain, which often differs from the realm.
+func splitPrincipal(username, realm string, cfg *config.Config) (string, string) {
+	user := username
+	domain := ""
+	if i := strings.LastIndex(user, "@"); i > 0 {
+		user, domain = user[:i], user[i+1:]
+		if realm == "" { realm = domain }
+	} else if i := indexOfDomainSep(user); i > 0 {
+		user, domain = user[i+1:], user[:i]
+	}
+	if realm == "" && cfg != nil { realm = cfg.LibDefaults.DefaultRealm }
+	if realm == "" { realm = domain }
+	return user, strings.ToUpper(realm)
+}
+
+type krbNegTransport struct {
+	base http.RoundTripper
+	cl   *krb5client.Client
+	// challenged is set once the server has asked for Negotiate; later requests then carry a
+	// ticket up front instead of paying for an anonymous 401 every time.
+	challenged atomic.Bool
+}
+
+func (t *krbNegTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewindable(req)
+	if !t.challenged.Load() {
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized || !offersScheme(resp, "negotiate") {
+			return resp, nil
+		}
+		drain(resp)
+		t.challenged.Store(true)
+	}
+	r2, err := retryRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	spn := servicePrincipal(req.URL)
+	if err := spnego.SetSPNEGOHeader(t.cl, r2, spn); err != nil {
+		return nil, fmt.Errorf("negotiate: obtain Kerberos service ticket for %s: %w", spn, err)
+	}
+	return t.base.RoundTrip(r2)
 }
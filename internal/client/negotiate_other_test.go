@@ -0,0 +1,195 @@
+//go:build !windows
+
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/iana/etypeID"
+	"github.com/jcmturner/gokrb5/v8/iana/nametype"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/service"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+const testRealm = "TEST.EXAMPLE"
+
+// standInKDC issues tickets the way a KDC would, from keytabs held in the test, and hands out a
+// Kerberos client whose credential cache already holds a TGT and a ticket for the service. No
+// network KDC is contacted.
+func standInKDC(t *testing.T, spn string) (*krb5client.Client, *keytab.Keytab) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Second)
+	end := now.Add(time.Hour)
+	svcKT := keytab.New()
+	if err := svcKT.AddEntry(spn, testRealm, "service-secret", now, 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatalf("service keytab: %v", err)
+	}
+	tgsKT := keytab.New()
+	if err := tgsKT.AddEntry("krbtgt/"+testRealm, testRealm, "krbtgt-secret", now, 1, etypeID.AES256_CTS_HMAC_SHA1_96); err != nil {
+		t.Fatalf("krbtgt keytab: %v", err)
+	}
+
+	cname := types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, "alice")
+	cc := &credentials.CCache{Version: 4}
+	cc.DefaultPrincipal.Realm = testRealm
+	cc.DefaultPrincipal.PrincipalName = cname
+	issue := func(sname types.PrincipalName, kt *keytab.Keytab) {
+		tkt, key, err := messages.NewTicket(cname, testRealm, sname, testRealm, types.NewKrbFlags(), kt, etypeID.AES256_CTS_HMAC_SHA1_96, 1, now, now, end, end)
+		if err != nil { t.Fatalf("issue ticket for %s: %v", sname.PrincipalNameString(), err) }
+		b, err := tkt.Marshal()
+		if err != nil { t.Fatalf("marshal ticket: %v", err) }
+		cred := &credentials.Credential{Key: key, AuthTime: now, StartTime: now, EndTime: end, RenewTill: end, Ticket: b}
+		cred.Client.Realm, cred.Client.PrincipalName = testRealm, cname
+		cred.Server.Realm, cred.Server.PrincipalName = testRealm, sname
+		cc.Credentials = append(cc.Credentials, cred)
+	}
+	issue(types.NewPrincipalName(nametype.KRB_NT_SRV_INST, "krbtgt/"+testRealm), tgsKT)
+	issue(types.NewPrincipalName(nametype.KRB_NT_PRINCIPAL, spn), svcKT)
+
+	cl, err := krb5client.NewFromCCache(cc, config.New(), krb5client.DisablePAFXFAST(true))
+	if err != nil { t.Fatalf("client from ccache: %v", err) }
+	return cl, svcKT
+}
+
+// spnegoServer challenges anonymous requests with Negotiate and accepts AP-REQs for its keytab.
+type spnegoServer struct {
+	mu       sync.Mutex
+	requests int
+	anon     int
+	bodies   []string
+}
+
+func newSPNEGOServer(t *testing.T, kt *keytab.Keytab) (*spnegoServer, *httptest.Server) {
+	s := &spnegoServer{}
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(b))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	})
+	auth := spnego.SPNEGOKRB5Authenticate(inner, kt, service.DecodePAC(false))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		if r.Header.Get("Authorization") == "" { s.anon++ }
+		s.mu.Unlock()
+		auth.ServeHTTP(w, r)
+	}))
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func TestKerberosTransportSPNEGO(t *testing.T) {
+	// httptest listens on loopback, which servicePrincipal maps to this host's name
+	cl, kt := standInKDC(t, servicePrincipal(&url.URL{Host: "127.0.0.1"}))
+	srv, ts := newSPNEGOServer(t, kt)
+	hc := &http.Client{Transport: newKerberosTransport(http.DefaultTransport.(*http.Transport).Clone(), cl)}
+
+	resp, err := hc.Post(ts.URL+"/api/v2/vms", "application/json", strings.NewReader(`{"name":"web01"}`))
+	if err != nil { t.Fatalf("request: %v", err) }
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { t.Fatalf("status = %d, want 200", resp.StatusCode) }
+	if srv.requests != 2 || srv.anon != 1 {
+		t.Fatalf("requests = %d (anonymous %d), want the 401 probe and one authenticated retry", srv.requests, srv.anon)
+	}
+	if len(srv.bodies) != 1 || srv.bodies[0] != `{"name":"web01"}` { t.Errorf("bodies = %q, want the original body replayed", srv.bodies) }
+
+	// After the first challenge the ticket is sent up front
+	resp, err = hc.Get(ts.URL + "/api/v2/vms")
+	if err != nil { t.Fatalf("second request: %v", err) }
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK { t.Fatalf("second status = %d, want 200", resp.StatusCode) }
+	if srv.requests != 3 || srv.anon != 1 { t.Errorf("requests = %d (anonymous %d), want 3 (1)", srv.requests, srv.anon) }
+}
+
+func TestKerberosTransportWithoutChallenge(t *testing.T) {
+	var got []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		if strings.HasSuffix(r.URL.Path, "/basic") {
+			w.Header().Set("WWW-Authenticate", `Basic realm="api"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer ts.Close()
+	cl, _ := standInKDC(t, servicePrincipal(&url.URL{Host: "127.0.0.1"}))
+	hc := &http.Client{Transport: newKerberosTransport(http.DefaultTransport.(*http.Transport).Clone(), cl)}
+
+	for _, p := range []string{"/open", "/basic"} {
+		resp, err := hc.Get(ts.URL + p)
+		if err != nil { t.Fatalf("%s: %v", p, err) }
+		resp.Body.Close()
+	}
+	if len(got) != 2 || got[0] != "" || got[1] != "" {
+		t.Errorf("Authorization headers = %q, want no ticket without a Negotiate challenge", got)
+	}
+}
+
+func TestSplitPrincipal(t *testing.T) {
+	cfg := config.New()
+	cfg.LibDefaults.DefaultRealm = "AD.EXAMPLE.COM"
+	cases := []struct {
+		username, realm string
+		cfg             *config.Config
+		wantUser        string
+		wantRealm       string
+	}{
+		{"alice@corp.example.com", "", cfg, "alice", "CORP.EXAMPLE.COM"},
+		{"alice@corp.example.com", "other.example", cfg, "alice", "OTHER.EXAMPLE"},
+		{`CORP\alice`, "", cfg, "alice", "AD.EXAMPLE.COM"},
+		{`CORP\alice`, "", nil, "alice", "CORP"},
+		{`CORP\alice`, "ad.example.com", nil, "alice", "AD.EXAMPLE.COM"},
+		{"alice", "", cfg, "alice", "AD.EXAMPLE.COM"},
+		{"alice", "", nil, "alice", ""},
+	}
+	for _, tc := range cases {
+		user, realm := splitPrincipal(tc.username, tc.realm, tc.cfg)
+		if user != tc.wantUser || realm != tc.wantRealm {
+			t.Errorf("splitPrincipal(%q, %q) = %q, %q; want %q, %q", tc.username, tc.realm, user, realm, tc.wantUser, tc.wantRealm)
+		}
+	}
+}
+
+func TestCCachePath(t *testing.T) {
+	dir := t.TempDir()
+	explicit := filepath.Join(dir, "explicit")
+	env := filepath.Join(dir, "env")
+	cases := []struct {
+		explicit, env string
+		want          string
+		wantErr       bool
+	}{
+		{explicit, "FILE:" + env, explicit, false},
+		{"", "FILE:" + env, env, false},
+		{"", env, env, false},
+		{"file:" + explicit, "", explicit, false},
+		{"", "KEYRING:persistent:1000", "", true},
+		{"KCM:", "", "", true},
+	}
+	for _, tc := range cases {
+		t.Setenv("KRB5CCNAME", tc.env)
+		got, err := ccachePath(tc.explicit)
+		if (err != nil) != tc.wantErr || got != tc.want {
+			t.Errorf("ccachePath(%q) with KRB5CCNAME=%q = %q, %v; want %q (error %v)", tc.explicit, tc.env, got, err, tc.want, tc.wantErr)
+		}
+	}
+	t.Setenv("KRB5CCNAME", "")
+	if got, _ := ccachePath(""); !strings.HasPrefix(got, "/tmp/krb5cc_") {
+		t.Errorf("default cache = %q, want /tmp/krb5cc_<uid>", got)
+	}
+}
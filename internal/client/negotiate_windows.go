@@ -7,12 +7,21 @@ import (
 	"encoding/base64"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/alexbrainman/sspi/negotiate"
 )
 
+// newNegotiateTransport selects the Windows flavour of Negotiate: impersonation when explicit
+// credentials are configured, otherwise the current user's SSPI credentials (Kerberos/NTLM).
+// Kerberos file settings (keytab, ccache, krb5.conf) are not used; SSPI owns the ticket cache.
+func newNegotiateTransport(base *http.Transport, auth AuthConfig) (http.RoundTripper, error) {
+	if auth.Username != "" {
+		return wrapNegotiateTransportWithImpersonation(base, auth.Username, auth.Password), nil
+	}
+	return wrapNegotiateTransport(base), nil
+}
+
 // wrapNegotiateTransport returns a RoundTripper that performs HTTP Negotiate (Kerberos/NTLM) using
 // the current Windows user credentials via SSPI. It falls back to the provided base transport for
 // non-authenticated requests or when the server does not challenge with Negotiate.
@@ -72,13 +81,7 @@ func (t *sspiNegTransport) RoundTrip(req *http.Request) (*http.Response, error)
 	resp.Body.Close()
 
 	// Create SSPI Negotiate client context for current user to target SPN HTTP/hostname
-	spnHost := req.URL.Hostname()
-	if spnHost == "localhost" || spnHost == "127.0.0.1" || spnHost == "::1" {
-		if hn, err := os.Hostname(); err == nil && hn != "" {
-			spnHost = hn
-		}
-	}
-	spn := "HTTP/" + spnHost
+	spn := servicePrincipal(req.URL)
 	cred, err := negotiate.AcquireCurrentUserCredentials()
 	if err != nil {
 		return nil, err
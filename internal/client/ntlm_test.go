@@ -0,0 +1,163 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf16"
+)
+
+// ntlmServer is an in-process endpoint that runs the server half of the NTLM handshake under one
+// WWW-Authenticate scheme and records who authenticated.
+type ntlmServer struct {
+	scheme string // NTLM | Negotiate
+	// kerberosOnly answers the negotiate message with a bare challenge, like a host with NTLM disabled
+	kerberosOnly bool
+
+	mu     sync.Mutex
+	probes int // requests without an Authorization header
+	user   string
+	domain string
+	body   string
+}
+
+func (s *ntlmServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	h := r.Header.Get("Authorization")
+	if h == "" {
+		s.probes++
+		w.Header().Set("WWW-Authenticate", s.scheme)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	prefix := s.scheme + " "
+	if !strings.HasPrefix(h, prefix) {
+		http.Error(w, "wrong scheme "+h, http.StatusBadRequest)
+		return
+	}
+	msg, err := base64.StdEncoding.DecodeString(h[len(prefix):])
+	if err != nil || len(msg) < 12 || string(msg[:8]) != "NTLMSSP\x00" {
+		http.Error(w, "bad token", http.StatusBadRequest)
+		return
+	}
+	switch binary.LittleEndian.Uint32(msg[8:12]) {
+	case 1:
+		if s.kerberosOnly {
+			w.Header().Set("WWW-Authenticate", s.scheme)
+		} else {
+			w.Header().Set("WWW-Authenticate", prefix+base64.StdEncoding.EncodeToString(ntlmChallenge("CORP")))
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	case 3:
+		s.domain = ntlmField(msg, 28)
+		s.user = ntlmField(msg, 36)
+		b, _ := io.ReadAll(r.Body)
+		s.body = string(b)
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "unexpected message", http.StatusBadRequest)
+	}
+}
+
+// ntlmChallenge builds a minimal NTLMv2 challenge message for target.
+func ntlmChallenge(target string) []byte {
+	name := utf16le(target)
+	info := []byte{0, 0, 0, 0} // MsvAvEOL
+	const flags = 0x00000001 | 0x00000004 | 0x00000200 | 0x00080000 | 0x00800000 // unicode, request target, NTLM, extended session security, target info
+	msg := make([]byte, 48)
+	copy(msg, "NTLMSSP\x00")
+	binary.LittleEndian.PutUint32(msg[8:], 2)
+	binary.LittleEndian.PutUint16(msg[12:], uint16(len(name)))
+	binary.LittleEndian.PutUint16(msg[14:], uint16(len(name)))
+	binary.LittleEndian.PutUint32(msg[16:], 48)
+	binary.LittleEndian.PutUint32(msg[20:], flags)
+	copy(msg[24:32], "01234567")
+	binary.LittleEndian.PutUint16(msg[40:], uint16(len(info)))
+	binary.LittleEndian.PutUint16(msg[42:], uint16(len(info)))
+	binary.LittleEndian.PutUint32(msg[44:], uint32(48+len(name)))
+	return append(append(msg, name...), info...)
+}
+
+// ntlmField decodes the UTF-16 payload field whose descriptor starts at off.
+func ntlmField(msg []byte, off int) string {
+	n := int(binary.LittleEndian.Uint16(msg[off:]))
+	start := int(binary.LittleEndian.Uint32(msg[off+4:]))
+	if n == 0 || start+n > len(msg) { return "" }
+	u := make([]uint16, n/2)
+	for i := range u { u[i] = binary.LittleEndian.Uint16(msg[start+2*i:]) }
+	return string(utf16.Decode(u))
+}
+
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) { b = binary.LittleEndian.AppendUint16(b, u) }
+	return b
+}
+
+func newNTLMTestClient(username string) *http.Client {
+	return &http.Client{Transport: newNTLMTransport(http.DefaultTransport.(*http.Transport).Clone(), username, "secret")}
+}
+
+func TestNTLMHandshake(t *testing.T) {
+	cases := []struct {
+		name, scheme, username string
+		wantUser, wantDomain   string
+	}{
+		{"down-level name over NTLM", "NTLM", `CORP\alice`, "alice", "CORP"},
+		{"down-level name over Negotiate", "Negotiate", `CORP\alice`, "alice", "CORP"},
+		{"UPN over NTLM", "NTLM", "alice@corp.example", "alice@corp.example", ""},
+		{"UPN over Negotiate", "Negotiate", "alice@corp.example", "alice@corp.example", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := &ntlmServer{scheme: tc.scheme}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			cl := newNTLMTestClient(tc.username)
+
+			resp, err := cl.Post(ts.URL+"/api/v2/vms", "application/json", strings.NewReader(`{"name":"web01"}`))
+			if err != nil { t.Fatalf("request: %v", err) }
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK { t.Fatalf("status = %d, want 200", resp.StatusCode) }
+			if srv.user != tc.wantUser || srv.domain != tc.wantDomain {
+				t.Errorf("authenticated as %q in %q, want %q in %q", srv.user, srv.domain, tc.wantUser, tc.wantDomain)
+			}
+			if srv.body != `{"name":"web01"}` { t.Errorf("body after handshake = %q", srv.body) }
+
+			// The accepted scheme is remembered, so the next request skips the anonymous probe
+			resp, err = cl.Get(ts.URL + "/api/v2/vms")
+			if err != nil { t.Fatalf("second request: %v", err) }
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK { t.Fatalf("second status = %d, want 200", resp.StatusCode) }
+			if srv.probes != 1 { t.Errorf("anonymous probes = %d, want 1", srv.probes) }
+		})
+	}
+}
+
+func TestNTLMKerberosOnlyServer(t *testing.T) {
+	ts := httptest.NewServer(&ntlmServer{scheme: "Negotiate", kerberosOnly: true})
+	defer ts.Close()
+	_, err := newNTLMTestClient(`CORP\alice`).Get(ts.URL + "/api/v2/vms")
+	if err == nil { t.Fatal("expected an error from a Kerberos-only server") }
+	if !strings.Contains(err.Error(), "Kerberos only") || !strings.Contains(err.Error(), `auth.method = "negotiate"`) {
+		t.Errorf("error does not point at negotiate: %v", err)
+	}
+}
+
+func TestNTLMNoSupportedScheme(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="api"`)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	_, err := newNTLMTestClient(`CORP\alice`).Get(ts.URL)
+	if err == nil || !strings.Contains(err.Error(), "did not offer NTLM or Negotiate") {
+		t.Fatalf("err = %v, want the missing-scheme diagnostic", err)
+	}
+}
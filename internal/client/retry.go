@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryMaxWait = 30 * time.Second
+	retryBaseDelay      = 500 * time.Millisecond
+)
+
+// idempotentPostSuffixes lists POST endpoints that are safe to repeat: re-sending them converges on
+// the same host state. Anything not listed here (create, clone enqueue, delete) is sent exactly once.
+var idempotentPostSuffixes = []string{":start", ":stop", ":save", ":pause", ":resume", ":resize", ":convert", ":connect", ":disconnect", ":vlan"}
+
+// idempotentPostSegments lists path segments under which every POST is a declarative setter.
+var idempotentPostSegments = []string{"/firmware/"}
+
+// retryPolicy decides whether and when a failed request is attempted again.
+type retryPolicy struct {
+	maxRetries int
+	maxWait    time.Duration
+}
+
+func newRetryPolicy(cfg Config) retryPolicy {
+	p := retryPolicy{maxRetries: defaultMaxRetries, maxWait: defaultRetryMaxWait}
+	if cfg.MaxRetries != nil { p.maxRetries = *cfg.MaxRetries }
+	if p.maxRetries < 0 { p.maxRetries = 0 }
+	if cfg.RetryMaxWaitSeconds > 0 { p.maxWait = time.Duration(cfg.RetryMaxWaitSeconds) * time.Second }
+	return p
+}
+
+// isIdempotent reports whether a request may be retried without side effects.
+func isIdempotent(method, path string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut:
+		// PUT replaces settings wholesale, so repeating it converges on the same state
+		return true
+	case http.MethodPost:
+		p, _, _ := strings.Cut(path, "?")
+		for _, s := range idempotentPostSuffixes {
+			if strings.HasSuffix(p, s) { return true }
+		}
+		for _, s := range idempotentPostSegments {
+			if strings.Contains(p, s) { return true }
+		}
+	}
+	return false
+}
+
+// retryableStatus reports whether the status signals a transient condition: throttling, a gateway
+// hiccup, or a 409 while Hyper-V is still finishing a previous operation on the VM.
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryableErr reports whether a transport error is worth another attempt. Cancellation by the
+// caller (Terraform interrupt or deadline) never is.
+func retryableErr(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil { return false }
+	var te *tokenError
+	if errors.As(err, &te) { return false }
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// backoff returns the delay before retry number attempt (1-based): jittered exponential growth
+// capped at maxWait, overridden by a server-provided Retry-After when present.
+func (p retryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > p.maxWait { d = p.maxWait }
+			return d
+		}
+	}
+	d := retryBaseDelay << uint(attempt-1)
+	if d <= 0 || d > p.maxWait { d = p.maxWait }
+	// Equal jitter: keep half the delay, randomize the rest to spread concurrent retries
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter accepts both delta-seconds and HTTP-date forms.
+func parseRetryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" { return 0, false }
+	if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+		return time.Duration(n) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 { d = 0 }
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepCtx waits for d or until ctx is done, whichever comes first.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIsIdempotent(t *testing.T) {
+	cases := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodGet, "/api/v2/vms/web01", true},
+		{http.MethodPut, "/api/v2/vms/web01/memory/config", true},
+		{http.MethodDelete, "/api/v2/vms/web01", false},
+		{http.MethodPatch, "/api/v2/vms/web01", false},
+		{http.MethodPost, "/api/v2/vms/web01:start", true},
+		{http.MethodPost, "/api/v2/vms/web01:stop?force=true", true},
+		{http.MethodPost, "/api/v2/vms/web01:save", true},
+		{http.MethodPost, "/api/v2/vms/web01:pause", true},
+		{http.MethodPost, "/api/v2/vms/web01:resume", true},
+		{http.MethodPost, "/api/v2/disks:resize", true},
+		{http.MethodPost, "/api/v2/disks:convert", true},
+		{http.MethodPost, "/api/v2/vms/web01/adapters/nic0:connect", true},
+		{http.MethodPost, "/api/v2/vms/web01/adapters/nic0:disconnect", true},
+		{http.MethodPost, "/api/v2/vms/web01/adapters/nic0:vlan", true},
+		{http.MethodPost, "/api/v2/vms/web01/firmware/secure-boot", true},
+		{http.MethodPost, "/api/v2/vms/web01/firmware/first-boot", true},
+		// Creates, enqueues and deletes are sent exactly once
+		{http.MethodPost, "/api/v2/vms", false},
+		{http.MethodPost, "/api/v2/disks/clone:prepare", false},
+		{http.MethodPost, "/api/v2/disks/clone", false},
+		{http.MethodPost, "/api/v2/disks/clone/tasks/42:cancel", false},
+		{http.MethodPost, "/api/v2/vms/web01/disks", false},
+		{http.MethodPost, "/api/v2/vms/web01/disks:detach", false},
+		{http.MethodPost, "/api/v2/disks:delete", false},
+		{http.MethodPost, "/api/v2/vms/web01:delete-prepare", false},
+		{http.MethodPost, "/api/v2/vms/web01:delete", false},
+		{http.MethodPost, "/api/v2/vms/web01/adapters", false},
+		{http.MethodPost, "/api/v2/vms/web01/adapters/nic0:delete", false},
+		{http.MethodPost, "/api/v2/vms/web01/security/key-protector", false},
+		{http.MethodPost, "/api/v2/vms/web01/security/tpm", false},
+		// A suffix inside a name or the query does not count
+		{http.MethodPost, "/api/v2/vms/web:start:delete", false},
+		{http.MethodPost, "/api/v2/vms/web:start/adapters", false},
+		{http.MethodPost, "/api/v2/vms?x=:start", false},
+	}
+	for _, tc := range cases {
+		if got := isIdempotent(tc.method, tc.path); got != tc.want {
+			t.Errorf("isIdempotent(%s %s) = %v, want %v", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	for _, code := range []int{408, 409, 429, 502, 503, 504} {
+		if !retryableStatus(code) { t.Errorf("retryableStatus(%d) = false, want true", code) }
+	}
+	for _, code := range []int{400, 401, 403, 404, 422, 500, 501} {
+		if retryableStatus(code) { t.Errorf("retryableStatus(%d) = true, want false", code) }
+	}
+}
+
+func TestBackoffBounds(t *testing.T) {
+	p := retryPolicy{maxRetries: 10, maxWait: 2 * time.Second}
+	for attempt := 1; attempt <= 10; attempt++ {
+		want := retryBaseDelay << uint(attempt-1)
+		if want > p.maxWait { want = p.maxWait }
+		for i := 0; i < 20; i++ {
+			if d := p.backoff(attempt, nil); d < want/2 || d > want {
+				t.Fatalf("backoff(%d) = %s, want within [%s, %s]", attempt, d, want/2, want)
+			}
+		}
+	}
+	retryAfter := func(v string) *http.Response { return &http.Response{Header: http.Header{"Retry-After": {v}}} }
+	if d := p.backoff(1, retryAfter("1")); d != time.Second { t.Errorf("Retry-After: 1 -> %s, want 1s", d) }
+	if d := p.backoff(1, retryAfter("120")); d != p.maxWait { t.Errorf("Retry-After: 120 -> %s, want capped at %s", d, p.maxWait) }
+	if d := p.backoff(1, retryAfter(time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat))); d != 0 {
+		t.Errorf("past Retry-After date -> %s, want 0", d)
+	}
+	if d, ok := parseRetryAfter(time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)); !ok || d < 8*time.Second || d > 10*time.Second {
+		t.Errorf("parseRetryAfter(date in 10s) = %s, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok { t.Error("parseRetryAfter(soon) accepted") }
+}
+
+// flakyServer fails the first n requests with status (and the Retry-After value, when set), then
+// answers 200, recording when each request arrived.
+type flakyServer struct {
+	status     int
+	retryAfter string
+	n          int
+
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.times = append(s.times, time.Now())
+	if len(s.times) > s.n {
+		_, _ = w.Write([]byte("{}"))
+		return
+	}
+	if s.retryAfter != "" { w.Header().Set("Retry-After", s.retryAfter) }
+	w.WriteHeader(s.status)
+}
+
+func (s *flakyServer) gap(i int) time.Duration { return s.times[i].Sub(s.times[i-1]) }
+
+func newRetryTestClient(t *testing.T, srv http.Handler, maxRetries, maxWaitSeconds int) *Client {
+	t.Helper()
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	c, err := New(Config{Endpoint: ts.URL, MaxRetries: &maxRetries, RetryMaxWaitSeconds: maxWaitSeconds})
+	if err != nil { t.Fatal(err) }
+	return c
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	srv := &flakyServer{status: http.StatusTooManyRequests, retryAfter: "1", n: 1}
+	c := newRetryTestClient(t, srv, 3, 30)
+	if _, err := c.GetVm(context.Background(), "web01"); err != nil { t.Fatalf("GetVm: %v", err) }
+	if len(srv.times) != 2 { t.Fatalf("requests = %d, want 2", len(srv.times)) }
+	if g := srv.gap(1); g < time.Second { t.Errorf("retried after %s, want Retry-After's 1s", g) }
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	srv := &flakyServer{status: http.StatusTooManyRequests, retryAfter: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), n: 1}
+	c := newRetryTestClient(t, srv, 3, 30)
+	if _, err := c.GetVm(context.Background(), "web01"); err != nil { t.Fatalf("GetVm: %v", err) }
+	if len(srv.times) != 2 { t.Fatalf("requests = %d, want 2", len(srv.times)) }
+	// A date already passed means retry now, not after the exponential backoff
+	if g := srv.gap(1); g > 200*time.Millisecond { t.Errorf("retried after %s, want immediately", g) }
+}
+
+func TestRetryBackoffBoundedByMaxWait(t *testing.T) {
+	// 120s from the server is capped at retry_max_wait_seconds, and so is the exponential backoff
+	srv := &flakyServer{status: http.StatusServiceUnavailable, retryAfter: "120", n: 10}
+	c := newRetryTestClient(t, srv, 1, 1)
+	start := time.Now()
+	_, err := c.GetVm(context.Background(), "web01")
+	var ae *ApiError
+	if !errors.As(err, &ae) || ae.Status != http.StatusServiceUnavailable || ae.Retries != 1 {
+		t.Fatalf("err = %v, want a 503 after 1 retry", err)
+	}
+	if len(srv.times) != 2 { t.Fatalf("requests = %d, want 2", len(srv.times)) }
+	if el := time.Since(start); el < time.Second || el > 2*time.Second { t.Errorf("took %s, want about the 1s cap", el) }
+
+	srv = &flakyServer{status: http.StatusServiceUnavailable, n: 1}
+	c = newRetryTestClient(t, srv, 3, 1)
+	if _, err := c.GetVm(context.Background(), "web01"); err != nil { t.Fatalf("GetVm: %v", err) }
+	if g := srv.gap(1); g < retryBaseDelay/2 || g > time.Second { t.Errorf("first backoff = %s, want within [%s, 1s]", g, retryBaseDelay/2) }
+}
+
+func TestNoRetryForNonIdempotentPost(t *testing.T) {
+	for _, status := range []int{http.StatusServiceUnavailable, http.StatusConflict, http.StatusTooManyRequests} {
+		srv := &flakyServer{status: status, retryAfter: "0", n: 10}
+		c := newRetryTestClient(t, srv, 3, 1)
+		_, err := c.CreateVm(context.Background(), CreateVmRequest{Name: "web01"})
+		var ae *ApiError
+		if !errors.As(err, &ae) || ae.Status != status || ae.Retries != 0 { t.Errorf("%d: err = %v, want the status without retries", status, err) }
+		if len(srv.times) != 1 { t.Errorf("%d: requests = %d, want exactly 1", status, len(srv.times)) }
+	}
+}
+
+func TestNoRetryOnCancel(t *testing.T) {
+	srv := &flakyServer{status: http.StatusServiceUnavailable, retryAfter: "30", n: 10}
+	c := newRetryTestClient(t, srv, 3, 30)
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.GetVm(ctx, "web01"); err == nil { t.Fatal("expected an error") }
+	if el := time.Since(start); el > 2*time.Second { t.Errorf("took %s, want the wait cut short by the context", el) }
+	if len(srv.times) != 1 { t.Errorf("requests = %d, want 1", len(srv.times)) }
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLSConfig customizes server verification and client certificates for every auth method.
+type TLSConfig struct {
+	CAFile string
+	CAPEM  string
+	// ClientCert and ClientKey accept a file path or inline PEM.
+	ClientCert         string
+	ClientKey          string
+	ServerName         string
+	MinVersion         string // "1.2" | "1.3"
+	InsecureSkipVerify bool
+	// PinnedSPKI holds base64 SHA-256 digests of trusted SubjectPublicKeyInfo, optionally prefixed
+	// with "sha256//" as in curl's --pinnedpubkey.
+	PinnedSPKI []string
+}
+
+func (t *TLSConfig) empty() bool {
+	return t == nil || (t.CAFile == "" && t.CAPEM == "" && t.ClientCert == "" && t.ClientKey == "" &&
+		t.ServerName == "" && t.MinVersion == "" && !t.InsecureSkipVerify && len(t.PinnedSPKI) == 0)
+}
+
+// tokenTLSConfig derives the TLS settings for the OAuth2 token endpoint from the API's: only the CA
+// pool carries over, so an internal IdP can be trusted without aiming the API pins, server name or
+// client certificate at it. nil means Go's defaults.
+func tokenTLSConfig(api *tls.Config) *tls.Config {
+	if api == nil || api.RootCAs == nil { return nil }
+	return &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: api.RootCAs}
+}
+
+// buildTLSConfig translates TLSConfig into a crypto/tls configuration for the API endpoint. Custom
+// CAs are added to the system pool rather than replacing it, so a public CA still verifies the API.
+func buildTLSConfig(t *TLSConfig) (*tls.Config, error) {
+	if t.empty() { return nil, nil }
+	out := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: t.ServerName, InsecureSkipVerify: t.InsecureSkipVerify}
+
+	switch t.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		out.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("tls.min_version %q is not supported (expected 1.2 or 1.3)", t.MinVersion)
+	}
+
+	if t.CAFile != "" || t.CAPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil { pool = x509.NewCertPool() }
+		if t.CAFile != "" {
+			b, err := os.ReadFile(t.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("tls.ca_file: %w", err)
+			}
+			if !pool.AppendCertsFromPEM(b) {
+				return nil, fmt.Errorf("tls.ca_file %s contains no PEM certificates", t.CAFile)
+			}
+		}
+		if t.CAPEM != "" && !pool.AppendCertsFromPEM([]byte(t.CAPEM)) {
+			return nil, fmt.Errorf("tls.ca_pem contains no PEM certificates")
+		}
+		out.RootCAs = pool
+	}
+
+	if t.ClientCert != "" || t.ClientKey != "" {
+		if t.ClientCert == "" || t.ClientKey == "" {
+			return nil, fmt.Errorf("tls.client_cert and tls.client_key must be set together")
+		}
+		certPEM, err := pemOrFile(t.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("tls.client_cert: %w", err)
+		}
+		keyPEM, err := pemOrFile(t.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("tls.client_key: %w", err)
+		}
+		pair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		out.Certificates = []tls.Certificate{pair}
+	}
+
+	if len(t.PinnedSPKI) > 0 {
+		pins := map[string]bool{}
+		for _, p := range t.PinnedSPKI {
+			p = strings.TrimPrefix(strings.TrimSpace(p), "sha256//")
+			if b, err := base64.StdEncoding.DecodeString(p); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("tls.pinned_spki %q is not a base64 SHA-256 digest", p)
+			}
+			pins[p] = true
+		}
+		// Pins only count on certificates something vouched for: the verified chains, or with
+		// InsecureSkipVerify the leaf alone, since the rest of an unverified chain is whatever the
+		// server chose to send.
+		out.VerifyConnection = func(cs tls.ConnectionState) error {
+			var candidates []*x509.Certificate
+			if t.InsecureSkipVerify {
+				if len(cs.PeerCertificates) > 0 { candidates = cs.PeerCertificates[:1] }
+			} else {
+				for _, chain := range cs.VerifiedChains { candidates = append(candidates, chain...) }
+			}
+			for _, cert := range candidates {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				if pins[base64.StdEncoding.EncodeToString(sum[:])] { return nil }
+			}
+			if t.InsecureSkipVerify { return fmt.Errorf("tls: the server certificate does not match tls.pinned_spki") }
+			return fmt.Errorf("tls: no certificate in the verified server chain matches tls.pinned_spki")
+		}
+	}
+	return out, nil
+}
+
+func pemOrFile(v string) ([]byte, error) {
+	if strings.Contains(v, "-----BEGIN") { return []byte(v), nil }
+	return os.ReadFile(v)
+}
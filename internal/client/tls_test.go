@@ -0,0 +1,130 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its key, signed by parent (self-signed when parent is nil).
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCert(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil { t.Fatalf("key: %v", err) }
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+		tmpl.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		tmpl.KeyUsage = x509.KeyUsageDigitalSignature
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil { t.Fatalf("certificate %s: %v", cn, err) }
+	c, err := x509.ParseCertificate(der)
+	if err != nil { t.Fatalf("parse %s: %v", cn, err) }
+	return &testCert{cert: c, key: key}
+}
+
+func (c *testCert) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}))
+}
+
+func (c *testCert) pin() string {
+	sum := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return "sha256//" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// tlsServer serves leaf followed by the extra chain certificates, whatever they are.
+func tlsServer(t *testing.T, leaf *testCert, extra ...*testCert) *httptest.Server {
+	chain := [][]byte{leaf.cert.Raw}
+	for _, c := range extra { chain = append(chain, c.cert.Raw) }
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: chain, PrivateKey: leaf.key}}}
+	ts.Config.ErrorLog = log.New(io.Discard, "", 0) // rejected handshakes are the point
+	ts.StartTLS()
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func getWithTLS(t *testing.T, url string, cfg *TLSConfig) error {
+	t.Helper()
+	tc, err := buildTLSConfig(cfg)
+	if err != nil { t.Fatalf("buildTLSConfig: %v", err) }
+	hc := &http.Client{Transport: &http.Transport{TLSClientConfig: tc}}
+	resp, err := hc.Get(url)
+	if err == nil { resp.Body.Close() }
+	return err
+}
+
+func TestPinnedSPKI(t *testing.T) {
+	ca := newTestCert(t, "corp ca", nil)
+	leaf := newTestCert(t, "hyperv-api", ca)
+	other := newTestCert(t, "other ca", nil)
+	ts := tlsServer(t, leaf, ca)
+
+	cases := []struct {
+		name    string
+		cfg     TLSConfig
+		wantErr string
+	}{
+		{"CA pin in the verified chain", TLSConfig{CAPEM: ca.pem(), PinnedSPKI: []string{ca.pin()}}, ""},
+		{"leaf pin in the verified chain", TLSConfig{CAPEM: ca.pem(), PinnedSPKI: []string{leaf.pin()}}, ""},
+		{"pin mismatch", TLSConfig{CAPEM: ca.pem(), PinnedSPKI: []string{other.pin()}}, "verified server chain"},
+		{"leaf pin without verification", TLSConfig{InsecureSkipVerify: true, PinnedSPKI: []string{leaf.pin()}}, ""},
+		{"CA pin without verification", TLSConfig{InsecureSkipVerify: true, PinnedSPKI: []string{ca.pin()}}, "server certificate does not match"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := getWithTLS(t, ts.URL, &tc.cfg)
+			if tc.wantErr == "" && err != nil { t.Fatalf("unexpected error: %v", err) }
+			if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// A server that appends the pinned certificate to a chain it controls must not pass the pin check.
+func TestPinnedSPKIAppendedToUntrustedChain(t *testing.T) {
+	pinned := newTestCert(t, "corp ca", nil)
+	rogue := newTestCert(t, "rogue ca", nil)
+	leaf := newTestCert(t, "hyperv-api", rogue)
+	ts := tlsServer(t, leaf, rogue, pinned)
+
+	// Verification on: the rogue CA is trusted, but the pinned cert is not part of the verified chain
+	err := getWithTLS(t, ts.URL, &TLSConfig{CAPEM: rogue.pem(), PinnedSPKI: []string{pinned.pin()}})
+	if err == nil || !strings.Contains(err.Error(), "pinned_spki") { t.Errorf("verified: err = %v, want a pin failure", err) }
+
+	// Verification off: only the leaf counts
+	err = getWithTLS(t, ts.URL, &TLSConfig{InsecureSkipVerify: true, PinnedSPKI: []string{pinned.pin()}})
+	if err == nil || !strings.Contains(err.Error(), "pinned_spki") { t.Errorf("insecure: err = %v, want a pin failure", err) }
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenRefreshSkew renews tokens this long before they expire so in-flight requests never carry a
+// token that lapses on the wire.
+const tokenRefreshSkew = 60 * time.Second
+
+type bearerToken struct {
+	Value  string
+	Expiry time.Time // zero when the source does not say
+}
+
+// tokenSource yields bearer tokens for auth.method = "bearer".
+type tokenSource interface {
+	token(ctx context.Context) (*bearerToken, error)
+}
+
+// tokenError marks failures to obtain a token; they are not retried as transient HTTP errors.
+type tokenError struct{ err error }
+
+func (e *tokenError) Error() string { return "bearer token: " + e.err.Error() }
+func (e *tokenError) Unwrap() error { return e.err }
+
+// newTokenSource picks the configured source: static token, token file, environment variable, or
+// the OAuth2 client-credentials flow. Exactly one is expected.
+func newTokenSource(auth AuthConfig, hc *http.Client) (*cachingTokenSource, error) {
+	var src tokenSource
+	n := 0
+	if auth.Token != "" { n++; src = staticTokenSource(auth.Token) }
+	if auth.TokenFile != "" { n++; src = fileTokenSource(auth.TokenFile) }
+	if auth.TokenEnv != "" { n++; src = envTokenSource(auth.TokenEnv) }
+	if auth.TokenURL != "" {
+		n++
+		if auth.ClientID == "" || auth.ClientSecret == "" {
+			return nil, fmt.Errorf("auth.token_url requires auth.client_id and auth.client_secret")
+		}
+		src = &clientCredentialsSource{tokenURL: auth.TokenURL, clientID: auth.ClientID, clientSecret: auth.ClientSecret, scopes: auth.Scopes, hc: hc}
+	}
+	switch n {
+	case 0:
+		return nil, fmt.Errorf("auth.method = \"bearer\" requires one of token, token_file, token_env or token_url")
+	case 1:
+		return &cachingTokenSource{src: src}, nil
+	}
+	return nil, fmt.Errorf("auth: set only one of token, token_file, token_env or token_url")
+}
+
+type staticTokenSource string
+
+func (s staticTokenSource) token(context.Context) (*bearerToken, error) {
+	return &bearerToken{Value: string(s), Expiry: jwtExpiry(string(s))}, nil
+}
+
+// fileTokenSource re-reads the file on every refresh so an external agent can rotate it.
+type fileTokenSource string
+
+func (s fileTokenSource) token(context.Context) (*bearerToken, error) {
+	b, err := os.ReadFile(string(s))
+	if err != nil {
+		return nil, err
+	}
+	v := strings.TrimSpace(string(b))
+	if v == "" {
+		return nil, fmt.Errorf("token file %s is empty", string(s))
+	}
+	return &bearerToken{Value: v, Expiry: jwtExpiry(v)}, nil
+}
+
+type envTokenSource string
+
+func (s envTokenSource) token(context.Context) (*bearerToken, error) {
+	v := strings.TrimSpace(os.Getenv(string(s)))
+	if v == "" {
+		return nil, fmt.Errorf("environment variable %s is empty", string(s))
+	}
+	return &bearerToken{Value: v, Expiry: jwtExpiry(v)}, nil
+}
+
+// clientCredentialsSource implements the OAuth2 client-credentials grant (RFC 6749 section 4.4).
+type clientCredentialsSource struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+	hc           *http.Client
+}
+
+func (s *clientCredentialsSource) token(ctx context.Context) (*bearerToken, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", s.clientID)
+	form.Set("client_secret", s.clientSecret)
+	if len(s.scopes) > 0 { form.Set("scope", strings.Join(s.scopes, " ")) }
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	var out struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int64  `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	_ = json.Unmarshal(data, &out)
+	if resp.StatusCode >= 400 || out.AccessToken == "" {
+		if out.Error != "" {
+			return nil, fmt.Errorf("token endpoint %s: %s: %s", s.tokenURL, out.Error, out.ErrorDescription)
+		}
+		return nil, fmt.Errorf("token endpoint %s -> %d | body=%s", s.tokenURL, resp.StatusCode, truncate(string(data), 512))
+	}
+	t := &bearerToken{Value: out.AccessToken}
+	if out.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(out.ExpiresIn) * time.Second)
+	} else {
+		t.Expiry = jwtExpiry(out.AccessToken)
+	}
+	return t, nil
+}
+
+// cachingTokenSource serves a cached token until it is close to expiry or invalidated by a 401.
+type cachingTokenSource struct {
+	src tokenSource
+	mu  sync.Mutex
+	tok *bearerToken
+}
+
+func (c *cachingTokenSource) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tok != nil && (c.tok.Expiry.IsZero() || time.Now().Add(tokenRefreshSkew).Before(c.tok.Expiry)) {
+		return c.tok.Value, nil
+	}
+	t, err := c.src.token(ctx)
+	if err != nil {
+		return "", &tokenError{err: err}
+	}
+	c.tok = t
+	return t.Value, nil
+}
+
+// Invalidate drops the cached token so the next request fetches a fresh one.
+func (c *cachingTokenSource) Invalidate() {
+	c.mu.Lock()
+	c.tok = nil
+	c.mu.Unlock()
+}
+
+// jwtExpiry returns the exp claim of a JWT, or zero for opaque tokens.
+func jwtExpiry(tok string) time.Time {
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 { return time.Time{} }
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil { return time.Time{} }
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if json.Unmarshal(b, &claims) != nil || claims.Exp == 0 { return time.Time{} }
+	return time.Unix(claims.Exp, 0)
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testJWT(claims string) string {
+	enc := base64.RawURLEncoding.EncodeToString
+	return enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(claims)) + "." + enc([]byte("sig"))
+}
+
+func TestJWTExpiry(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	if got := jwtExpiry(testJWT(fmt.Sprintf(`{"sub":"runner","exp":%d}`, exp.Unix()))); !got.Equal(exp) {
+		t.Errorf("jwtExpiry = %s, want %s", got, exp)
+	}
+	for _, tok := range []string{
+		"opaque-token",
+		"a.b",
+		testJWT(`{"sub":"runner"}`),
+		testJWT(`{"exp":"tomorrow"}`),
+		"header.!!notbase64!!.sig",
+	} {
+		if got := jwtExpiry(tok); !got.IsZero() { t.Errorf("jwtExpiry(%q) = %s, want zero", tok, got) }
+	}
+}
+
+// countingSource hands out tok-1, tok-2, ... each expiring after ttl (never when ttl is 0).
+type countingSource struct {
+	ttl   time.Duration
+	calls int
+}
+
+func (s *countingSource) token(context.Context) (*bearerToken, error) {
+	s.calls++
+	t := &bearerToken{Value: fmt.Sprintf("tok-%d", s.calls)}
+	if s.ttl > 0 { t.Expiry = time.Now().Add(s.ttl) }
+	return t, nil
+}
+
+func TestCachingTokenSource(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name      string
+		ttl       time.Duration
+		wantCalls int
+	}{
+		{"valid beyond the skew", tokenRefreshSkew + time.Minute, 1},
+		{"within the skew", tokenRefreshSkew - time.Second, 3},
+		{"no expiry", 0, 1},
+	}
+	for _, tc := range cases {
+		src := &countingSource{ttl: tc.ttl}
+		c := &cachingTokenSource{src: src}
+		for i := 0; i < 3; i++ {
+			if _, err := c.Token(ctx); err != nil { t.Fatalf("%s: %v", tc.name, err) }
+		}
+		if src.calls != tc.wantCalls { t.Errorf("%s: source called %d times, want %d", tc.name, src.calls, tc.wantCalls) }
+	}
+
+	src := &countingSource{}
+	c := &cachingTokenSource{src: src}
+	first, _ := c.Token(ctx)
+	c.Invalidate()
+	if second, _ := c.Token(ctx); second == first || src.calls != 2 { t.Errorf("after Invalidate got %q (%d fetches), want a fresh token", second, src.calls) }
+}
+
+func TestTokenSourceErrorsAreNotRetried(t *testing.T) {
+	t.Setenv("HYPERVAPI_TEST_TOKEN", "")
+	c := &cachingTokenSource{src: envTokenSource("HYPERVAPI_TEST_TOKEN")}
+	_, err := c.Token(context.Background())
+	var te *tokenError
+	if !errors.As(err, &te) || retryableErr(context.Background(), err) { t.Errorf("err = %v, want a non-retryable tokenError", err) }
+}
+
+// tokenEndpoint is a client-credentials endpoint that issues tok-1, tok-2, ... and keeps the last form.
+type tokenEndpoint struct {
+	mu     sync.Mutex
+	issued int
+	form   map[string]string
+	ctype  string
+}
+
+func (s *tokenEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = r.ParseForm()
+	s.ctype = r.Header.Get("Content-Type")
+	s.form = map[string]string{}
+	for k := range r.PostForm { s.form[k] = r.PostForm.Get(k) }
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost || s.form["client_secret"] != "s3cret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":"invalid_client","error_description":"bad secret"}`))
+		return
+	}
+	s.issued++
+	fmt.Fprintf(w, `{"access_token":"tok-%d","token_type":"Bearer","expires_in":3600}`, s.issued)
+}
+
+func TestClientCredentialsSource(t *testing.T) {
+	ep := &tokenEndpoint{}
+	ts := httptest.NewServer(ep)
+	defer ts.Close()
+	src := &clientCredentialsSource{tokenURL: ts.URL, clientID: "runner", clientSecret: "s3cret", scopes: []string{"hyperv.read", "hyperv.write"}, hc: ts.Client()}
+
+	tok, err := src.token(context.Background())
+	if err != nil { t.Fatal(err) }
+	if tok.Value != "tok-1" { t.Errorf("token = %q, want tok-1", tok.Value) }
+	if d := time.Until(tok.Expiry); d < 59*time.Minute || d > time.Hour { t.Errorf("expiry in %s, want expires_in's hour", d) }
+	want := map[string]string{"grant_type": "client_credentials", "client_id": "runner", "client_secret": "s3cret", "scope": "hyperv.read hyperv.write"}
+	for k, v := range want {
+		if ep.form[k] != v { t.Errorf("form %s = %q, want %q", k, ep.form[k], v) }
+	}
+	if len(ep.form) != len(want) { t.Errorf("form = %v, want only %v", ep.form, want) }
+	if ep.ctype != "application/x-www-form-urlencoded" { t.Errorf("Content-Type = %q", ep.ctype) }
+
+	src.clientSecret = "wrong"
+	if _, err := src.token(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid_client: bad secret") {
+		t.Errorf("err = %v, want the OAuth2 error", err)
+	}
+}
+
+func TestUnauthorizedRefetchesTokenOnce(t *testing.T) {
+	ep := &tokenEndpoint{}
+	idp := httptest.NewServer(ep)
+	defer idp.Close()
+	for _, tc := range []struct {
+		name    string
+		accept  string // token the API accepts; empty rejects all
+		wantErr bool
+	}{
+		{"rotated token", "tok-2", false},
+		{"revoked credentials", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ep.mu.Lock()
+			ep.issued = 0
+			ep.mu.Unlock()
+			var seen []string
+			api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = append(seen, r.Header.Get("Authorization"))
+				if tc.accept == "" || r.Header.Get("Authorization") != "Bearer "+tc.accept {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				_, _ = w.Write([]byte("{}"))
+			}))
+			defer api.Close()
+			c, err := New(Config{Endpoint: api.URL, Auth: AuthConfig{Method: "bearer", TokenURL: idp.URL, ClientID: "runner", ClientSecret: "s3cret"}})
+			if err != nil { t.Fatal(err) }
+			// Prime the cache so the first API call goes out with tok-1
+			if _, err := c.tokens.Token(context.Background()); err != nil { t.Fatal(err) }
+
+			_, err = c.GetVm(context.Background(), "web01")
+			if (err != nil) != tc.wantErr { t.Fatalf("err = %v, want error %v", err, tc.wantErr) }
+			if tc.wantErr && !IsUnauthorized(err) { t.Errorf("err = %v, want a 401", err) }
+			if len(seen) != 2 || seen[0] != "Bearer tok-1" || seen[1] != "Bearer tok-2" {
+				t.Errorf("API saw %q, want tok-1 then one retry with tok-2", seen)
+			}
+			if ep.issued != 2 { t.Errorf("tokens issued = %d, want 2", ep.issued) }
+		})
+	}
+}
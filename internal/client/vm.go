@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Vm is the typed view of GET /api/v2/vms/{name}.
+type Vm struct {
+	Name       string      `json:"name"`
+	ID         string      `json:"id"`
+	VmId       string      `json:"vmId"`
+	State      string      `json:"state"` // Running | Off | Saved | Paused | ...
+	Generation int         `json:"generation"`
+	CpuCount   int         `json:"cpuCount"`
+	MemoryMB   int         `json:"memoryMB"`
+	SwitchName string      `json:"switchName"`
+	Firmware   *VmFirmware `json:"firmware,omitempty"`
+	Security   *VmSecurity `json:"security,omitempty"`
+}
+
+type VmFirmware struct {
+	SecureBoot         *bool  `json:"secureBoot"`
+	SecureBootTemplate string `json:"secureBootTemplate"`
+	BootOrder          []BootDevice `json:"bootOrder"`
+	PreferredNetworkBootProtocol string `json:"preferredNetworkBootProtocol"` // IPv4 | IPv6
+	PauseAfterBootFailure *bool `json:"pauseAfterBootFailure"`
+}
+
+// BootDevice is one entry of the Generation 2 boot order: a device type and, for disks and
+// network adapters, the VHD path or adapter name it refers to.
+type BootDevice struct {
+	Type string `json:"type"` // Disk | CD | Network
+	Ref  string `json:"ref,omitempty"`
+}
+
+// SetFirmwareRequest changes Generation 2 boot settings; nil fields are left as they are.
+// BootOrder lists devices first; devices it omits keep their relative order after them.
+type SetFirmwareRequest struct {
+	BootOrder                    []BootDevice `json:"bootOrder,omitempty"`
+	PreferredNetworkBootProtocol *string      `json:"preferredNetworkBootProtocol,omitempty"`
+	PauseAfterBootFailure        *bool        `json:"pauseAfterBootFailure,omitempty"`
+}
+
+type VmSecurity struct {
+	TPM               *bool `json:"tpm"`
+	EncryptionSupport *bool `json:"encryptionSupport"`
+}
+
+// Identifier returns the Hyper-V VM GUID, whichever field the server filled.
+func (v *Vm) Identifier() string {
+	if v.VmId != "" { return v.VmId }
+	return v.ID
+}
+
+// PowerState maps the Hyper-V state onto the resource's power values: "running", "stopped", or the
+// lower-cased host state (e.g. "saved", "paused") for anything else.
+func (v *Vm) PowerState() string {
+	switch s := strings.ToLower(v.State); s {
+	case "running", "on":
+		return "running"
+	case "off", "stopped":
+		return "stopped"
+	default:
+		return s
+	}
+}
+
+func (v *Vm) Running() bool { return v.PowerState() == "running" }
+
+// GetVm returns the VM's minimal view. Callers branch on IsNotFound(err) to detect a VM that no
+// longer exists.
+func (c *Client) GetVm(ctx context.Context, name string) (*Vm, error) {
+	var out Vm
+	_, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/vms/%s", url.PathEscape(name)), nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListVms returns every VM visible to the caller.
+func (c *Client) ListVms(ctx context.Context) ([]Vm, error) {
+	var out []Vm
+	_, err := c.do(ctx, http.MethodGet, "/api/v2/vms", nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *Client) GetFirmware(ctx context.Context, name string) (*VmFirmware, error) {
+	var out VmFirmware
+	path := fmt.Sprintf("/api/v2/vms/%s/firmware", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if err != nil { return nil, err }
+	return &out, nil
+}
+
+func (c *Client) SetFirmware(ctx context.Context, name string, req SetFirmwareRequest) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/firmware", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPut, path, req, nil)
+	return err
+}
+
+func (c *Client) GetSecurity(ctx context.Context, name string) (*VmSecurity, error) {
+	var out VmSecurity
+	path := fmt.Sprintf("/api/v2/vms/%s/security", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if err != nil { return nil, err }
+	return &out, nil
+}
+
+// EnsureKeyProtector gives the VM a local key protector unless it already has one. A vTPM and
+// encryption support both need it.
+func (c *Client) EnsureKeyProtector(ctx context.Context, name string) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/security/key-protector", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{"mode": "local"}, nil)
+	return err
+}
+
+// SetTPM enables or disables the virtual TPM. The VM must be off.
+func (c *Client) SetTPM(ctx context.Context, name string, enabled bool) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/security/tpm", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{"enabled": enabled}, nil)
+	return err
+}
+
+// SetEncryptionSupport toggles encryption of VM state and migration traffic. The VM must be off.
+func (c *Client) SetEncryptionSupport(ctx context.Context, name string, enabled bool) error {
+	path := fmt.Sprintf("/api/v2/vms/%s/security/encryption-support", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodPost, path, map[string]any{"enabled": enabled}, nil)
+	return err
+}
+
+// AttachedDisk is a virtual hard disk connected to a VM controller.
+type AttachedDisk struct {
+	Path               string `json:"path"`
+	ControllerType     string `json:"controllerType"` // SCSI | IDE
+	ControllerNumber   int    `json:"controllerNumber"`
+	ControllerLocation int    `json:"controllerLocation"`
+}
+
+func (c *Client) ListAttachedDisks(ctx context.Context, name string) ([]AttachedDisk, error) {
+	var out []AttachedDisk
+	path := fmt.Sprintf("/api/v2/vms/%s/disks/attached", url.PathEscape(name))
+	_, err := c.do(ctx, http.MethodGet, path, nil, &out)
+	if err != nil { return nil, err }
+	return out, nil
+}
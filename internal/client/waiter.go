@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// TaskState is the normalized lifecycle state of a server-side async task.
+type TaskState string
+
+const (
+	TaskPending   TaskState = "pending"
+	TaskRunning   TaskState = "running"
+	TaskSucceeded TaskState = "succeeded"
+	TaskFailed    TaskState = "failed"
+	TaskCanceled  TaskState = "canceled"
+	TaskUnknown   TaskState = "unknown"
+)
+
+// Terminal reports whether no further transitions are expected.
+func (s TaskState) Terminal() bool {
+	return s == TaskSucceeded || s == TaskFailed || s == TaskCanceled
+}
+
+// NormalizeTaskState maps the various status spellings used by API task endpoints onto TaskState.
+func NormalizeTaskState(status string) TaskState {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "queued", "pending", "created", "notstarted", "waiting":
+		return TaskPending
+	case "running", "inprogress", "in_progress", "copying", "started":
+		return TaskRunning
+	case "succeeded", "success", "successful", "completed", "complete", "done":
+		return TaskSucceeded
+	case "failed", "failure", "error", "faulted":
+		return TaskFailed
+	case "canceled", "cancelled", "aborted":
+		return TaskCanceled
+	}
+	return TaskUnknown
+}
+
+// TaskStatus is one observation of an async task, as reported by a TaskWaiter's Poll func.
+type TaskStatus struct {
+	State TaskState
+	// Status is the state as the server spelled it, kept for diagnostics.
+	Status string
+	// Done and Total are optional progress counters (bytes for clone tasks).
+	Done  *int64
+	Total *int64
+	Error string
+}
+
+// TaskWaiter polls any task-returning endpoint until it reaches a terminal state.
+type TaskWaiter struct {
+	// Name identifies the task in logs and errors, e.g. "clone 3f2a".
+	Name string
+	Poll func(ctx context.Context) (*TaskStatus, error)
+	// Cancel is optional; when set it is invoked if ctx ends before the task does.
+	Cancel func(ctx context.Context) error
+
+	MinInterval time.Duration
+	MaxInterval time.Duration
+	// ProgressEvery throttles progress log lines.
+	ProgressEvery time.Duration
+	// MaxPollErrors is the number of consecutive failed polls tolerated before giving up. Polls that
+	// return no status or one NormalizeTaskState does not recognize count as failed.
+	MaxPollErrors int
+}
+
+// Wait blocks until the task succeeds, fails, is canceled, or ctx ends. Poll intervals back off
+// from MinInterval to MaxInterval so short tasks finish quickly and long ones don't hammer the API.
+func (w TaskWaiter) Wait(ctx context.Context) (*TaskStatus, error) {
+	minI, maxI := w.MinInterval, w.MaxInterval
+	if minI <= 0 { minI = time.Second }
+	if maxI < minI { maxI = 10 * time.Second }
+	every := w.ProgressEvery
+	if every <= 0 { every = 15 * time.Second }
+	maxErrs := w.MaxPollErrors
+	if maxErrs <= 0 { maxErrs = 5 }
+
+	start := time.Now()
+	var lastLog time.Time
+	interval := minI
+	pollErrs := 0
+	for {
+		st, err := w.Poll(ctx)
+		// An unreadable state would otherwise be polled silently until the Terraform timeout
+		if err == nil && st == nil {
+			err = errors.New("no task status returned")
+		} else if err == nil && st.State == TaskUnknown {
+			err = fmt.Errorf("unrecognized task status %q", st.Status)
+		}
+		switch {
+		case err != nil && ctx.Err() == nil:
+			if IsNotFound(err) {
+				return nil, fmt.Errorf("%s: task no longer exists on the server: %w", w.Name, err)
+			}
+			pollErrs++
+			tflog.Warn(ctx, "task.poll_error", map[string]any{"task": w.Name, "error": err.Error(), "consecutive": pollErrs})
+			if pollErrs >= maxErrs {
+				return nil, fmt.Errorf("%s: polling failed %d times in a row: %w", w.Name, pollErrs, err)
+			}
+		case err == nil:
+			pollErrs = 0
+			if time.Since(lastLog) >= every || st.State.Terminal() {
+				logProgress(ctx, w.Name, st, time.Since(start))
+				lastLog = time.Now()
+			}
+			switch st.State {
+			case TaskSucceeded:
+				return st, nil
+			case TaskFailed:
+				msg := st.Error
+				if msg == "" { msg = "task reported failure" }
+				return st, fmt.Errorf("%s failed: %s", w.Name, msg)
+			case TaskCanceled:
+				return st, fmt.Errorf("%s was canceled on the server", w.Name)
+			}
+		}
+
+		if err := sleepCtx(ctx, interval); err != nil {
+			return nil, w.abandon(ctx, start)
+		}
+		interval = interval * 3 / 2
+		if interval > maxI { interval = maxI }
+	}
+}
+
+// abandon cancels the server task (best effort) after ctx ended and builds the resulting error.
+func (w TaskWaiter) abandon(ctx context.Context, start time.Time) error {
+	cause := ctx.Err()
+	reason := "interrupted"
+	if errors.Is(cause, context.DeadlineExceeded) { reason = "timed out" }
+	if w.Cancel != nil {
+		// ctx is already done; give the cancel call its own short-lived context
+		cctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		if err := w.Cancel(cctx); err != nil {
+			tflog.Warn(ctx, "task.cancel_failed", map[string]any{"task": w.Name, "error": err.Error()})
+			return fmt.Errorf("%s %s after %s (server-side cancel failed: %v): %w", reason, w.Name, time.Since(start).Round(time.Second), err, cause)
+		}
+		tflog.Info(ctx, "task.canceled", map[string]any{"task": w.Name})
+		return fmt.Errorf("%s %s after %s; server task canceled: %w", reason, w.Name, time.Since(start).Round(time.Second), cause)
+	}
+	return fmt.Errorf("%s %s after %s: %w", reason, w.Name, time.Since(start).Round(time.Second), cause)
+}
+
+func logProgress(ctx context.Context, name string, st *TaskStatus, elapsed time.Duration) {
+	fields := map[string]any{"task": name, "state": string(st.State), "elapsed": elapsed.Round(time.Second).String()}
+	if st.Done != nil { fields["done"] = *st.Done }
+	if st.Total != nil { fields["total"] = *st.Total }
+	if st.Done != nil && st.Total != nil && *st.Total > 0 {
+		done, total := *st.Done, *st.Total
+		fields["percent"] = fmt.Sprintf("%.1f", float64(done)*100/float64(total))
+		if done > 0 && done < total && elapsed > 0 {
+			rate := float64(done) / elapsed.Seconds()
+			eta := time.Duration(float64(total-done) / rate * float64(time.Second))
+			fields["eta"] = eta.Round(time.Second).String()
+			fields["rate_mb_s"] = fmt.Sprintf("%.1f", rate/(1<<20))
+		}
+	}
+	tflog.Info(ctx, "task.progress", fields)
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizeTaskState(t *testing.T) {
+	cases := map[string]TaskState{
+		"Queued": TaskPending, "pending": TaskPending, "Created": TaskPending, "NotStarted": TaskPending, "waiting": TaskPending,
+		"Running": TaskRunning, "InProgress": TaskRunning, "in_progress": TaskRunning, "Copying": TaskRunning, "started": TaskRunning,
+		"Succeeded": TaskSucceeded, "success": TaskSucceeded, "Successful": TaskSucceeded, "Completed": TaskSucceeded, "complete": TaskSucceeded, " done ": TaskSucceeded,
+		"Failed": TaskFailed, "failure": TaskFailed, "Error": TaskFailed, "Faulted": TaskFailed,
+		"Canceled": TaskCanceled, "cancelled": TaskCanceled, "Aborted": TaskCanceled,
+		"": TaskUnknown, "paused": TaskUnknown, "in progress": TaskUnknown,
+	}
+	for in, want := range cases {
+		if got := NormalizeTaskState(in); got != want { t.Errorf("NormalizeTaskState(%q) = %s, want %s", in, got, want) }
+	}
+	for _, s := range []TaskState{TaskSucceeded, TaskFailed, TaskCanceled} {
+		if !s.Terminal() { t.Errorf("%s not terminal", s) }
+	}
+	for _, s := range []TaskState{TaskPending, TaskRunning, TaskUnknown} {
+		if s.Terminal() { t.Errorf("%s terminal", s) }
+	}
+}
+
+// scripted is a Poll func that replays steps, repeating the last one, and records when each
+// poll happened.
+type scripted struct {
+	steps []func() (*TaskStatus, error)
+	times []time.Time
+}
+
+func (s *scripted) poll(context.Context) (*TaskStatus, error) {
+	s.times = append(s.times, time.Now())
+	i := len(s.times) - 1
+	if i >= len(s.steps) { i = len(s.steps) - 1 }
+	return s.steps[i]()
+}
+
+func state(status string) func() (*TaskStatus, error) {
+	return func() (*TaskStatus, error) { return &TaskStatus{State: NormalizeTaskState(status), Status: status}, nil }
+}
+
+func failedPoll(err error) func() (*TaskStatus, error) {
+	return func() (*TaskStatus, error) { return nil, err }
+}
+
+func fastWaiter(s *scripted) TaskWaiter {
+	return TaskWaiter{Name: "clone 42", Poll: s.poll, MinInterval: time.Millisecond, MaxInterval: 2 * time.Millisecond, MaxPollErrors: 3}
+}
+
+func TestWaitTerminalStates(t *testing.T) {
+	cases := []struct {
+		name    string
+		last    func() (*TaskStatus, error)
+		wantErr string
+	}{
+		{"succeeded", state("Completed"), ""},
+		{"failed with message", func() (*TaskStatus, error) { return &TaskStatus{State: TaskFailed, Error: "disk full"}, nil }, "clone 42 failed: disk full"},
+		{"failed without message", state("Faulted"), "clone 42 failed: task reported failure"},
+		{"canceled", state("Aborted"), "clone 42 was canceled on the server"},
+	}
+	for _, tc := range cases {
+		s := &scripted{steps: []func() (*TaskStatus, error){state("Queued"), state("Copying"), tc.last}}
+		st, err := fastWaiter(s).Wait(context.Background())
+		if tc.wantErr == "" && err != nil { t.Errorf("%s: %v", tc.name, err) }
+		if tc.wantErr != "" && (err == nil || err.Error() != tc.wantErr) { t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr) }
+		if st == nil || !st.State.Terminal() { t.Errorf("%s: status = %+v, want the terminal one", tc.name, st) }
+		if len(s.times) != 3 { t.Errorf("%s: polled %d times, want 3", tc.name, len(s.times)) }
+	}
+}
+
+func TestWaitBackoff(t *testing.T) {
+	s := &scripted{steps: []func() (*TaskStatus, error){state("Running"), state("Running"), state("Running"), state("Running"), state("Running"), state("Succeeded")}}
+	w := TaskWaiter{Name: "clone 42", Poll: s.poll, MinInterval: 50 * time.Millisecond, MaxInterval: 100 * time.Millisecond}
+	if _, err := w.Wait(context.Background()); err != nil { t.Fatal(err) }
+	// 50ms grows by half each poll and stops at 100ms; uncapped it would reach 168ms by the fourth gap
+	want := []time.Duration{50, 75, 100, 100, 100}
+	for i, ms := range want {
+		g := s.times[i+1].Sub(s.times[i])
+		if g < ms*time.Millisecond { t.Errorf("gap %d = %s, want at least %dms", i, g, ms) }
+	}
+	if g := s.times[4].Sub(s.times[3]); g >= 168*time.Millisecond { t.Errorf("gap 3 = %s, want capped at 100ms", g) }
+}
+
+func TestWaitCancelsServerTaskOnContextEnd(t *testing.T) {
+	for _, cancelErr := range []error{nil, errors.New("409 already finishing")} {
+		s := &scripted{steps: []func() (*TaskStatus, error){state("Running")}}
+		w := fastWaiter(s)
+		var cancelled int
+		var cancelCtxErr error
+		w.Cancel = func(ctx context.Context) error {
+			cancelled++
+			cancelCtxErr = ctx.Err()
+			return cancelErr
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := w.Wait(ctx)
+		cancel()
+		if cancelled != 1 { t.Fatalf("Cancel called %d times, want 1", cancelled) }
+		if cancelCtxErr != nil { t.Errorf("Cancel got a done context: %v", cancelCtxErr) }
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.HasPrefix(err.Error(), "timed out clone 42") {
+			t.Errorf("err = %v, want a timeout wrapping the deadline", err)
+		}
+		want := "server task canceled"
+		if cancelErr != nil { want = "server-side cancel failed: 409 already finishing" }
+		if !strings.Contains(err.Error(), want) { t.Errorf("err = %v, want %q", err, want) }
+	}
+
+	s := &scripted{steps: []func() (*TaskStatus, error){state("Running")}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := fastWaiter(s).Wait(ctx); !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "interrupted") {
+		t.Errorf("err = %v, want an interruption without Cancel set", err)
+	}
+}
+
+func TestWaitPollErrors(t *testing.T) {
+	boom := errors.New("connection reset")
+	cases := []struct {
+		name      string
+		steps     []func() (*TaskStatus, error)
+		wantErr   string
+		wantPolls int
+	}{
+		{"transient errors recover", []func() (*TaskStatus, error){failedPoll(boom), failedPoll(boom), state("Succeeded")}, "", 3},
+		{"repeated errors give up", []func() (*TaskStatus, error){failedPoll(boom)}, "polling failed 3 times in a row: connection reset", 3},
+		{"task gone", []func() (*TaskStatus, error){state("Running"), failedPoll(&ApiError{Status: http.StatusNotFound})}, "task no longer exists", 2},
+		{"no status", []func() (*TaskStatus, error){failedPoll(nil)}, "no task status returned", 3},
+		{"unrecognized status", []func() (*TaskStatus, error){state("Paused")}, `unrecognized task status "Paused"`, 3},
+		{"unknown then known", []func() (*TaskStatus, error){state("Paused"), state("Paused"), state("Running"), state("Paused"), state("Paused"), state("Done")}, "", 6},
+	}
+	for _, tc := range cases {
+		s := &scripted{steps: tc.steps}
+		_, err := fastWaiter(s).Wait(context.Background())
+		if tc.wantErr == "" && err != nil { t.Errorf("%s: %v", tc.name, err) }
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) { t.Errorf("%s: err = %v, want %q", tc.name, err, tc.wantErr) }
+		if len(s.times) != tc.wantPolls { t.Errorf("%s: polled %d times, want %d", tc.name, len(s.times), tc.wantPolls) }
+	}
+}
@@ -0,0 +1,32 @@
+// Package policy reports server policy findings (plan-disk warnings, disallowed paths) as Terraform
+// diagnostics, escalated to errors when the provider runs with strict = true.
+package policy
+
+import (
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+)
+
+// Notice surfaces a policy warning on at (path.Empty() for none): a warning by default, an error
+// under strict.
+func Notice(diags *diag.Diagnostics, strict bool, at path.Path, summary, detail string) {
+	if strict {
+		Violation(diags, at, summary, detail)
+		return
+	}
+	if len(at.Steps()) == 0 {
+		diags.AddWarning(summary, detail)
+		return
+	}
+	diags.AddAttributeWarning(at, summary, detail)
+}
+
+// Violation reports a policy warning that strict = true turned into an error.
+func Violation(diags *diag.Diagnostics, at path.Path, summary, detail string) {
+	detail += "\n\nProvider strict = true escalates policy warnings to errors."
+	if len(at.Steps()) == 0 {
+		diags.AddError(summary, detail)
+		return
+	}
+	diags.AddAttributeError(at, summary, detail)
+}
@@ -0,0 +1,66 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables consulted when the provider block leaves a setting unset.
+// Precedence: provider block > environment variable > built-in default.
+const (
+	envEndpoint       = "HYPERVAPI_ENDPOINT"
+	envProxy          = "HYPERVAPI_PROXY"
+	envTimeoutSeconds = "HYPERVAPI_TIMEOUT_SECONDS"
+	envLogHTTP        = "HYPERVAPI_LOG_HTTP"
+	envAuthMethod     = "HYPERVAPI_AUTH_METHOD"
+	envUsername       = "HYPERVAPI_USERNAME"
+	envPassword       = "HYPERVAPI_PASSWORD"
+	envToken          = "HYPERVAPI_TOKEN"
+	envTokenFile      = "HYPERVAPI_TOKEN_FILE"
+	envTokenURL       = "HYPERVAPI_TOKEN_URL"
+	envClientID       = "HYPERVAPI_CLIENT_ID"
+	envClientSecret   = "HYPERVAPI_CLIENT_SECRET"
+	envScopes         = "HYPERVAPI_SCOPES"
+)
+
+// stringOrEnv returns the configured value, else the environment variable, else "".
+func stringOrEnv(v types.String, env string) string {
+	if !v.IsNull() && !v.IsUnknown() { return v.ValueString() }
+	return os.Getenv(env)
+}
+
+// configured reports whether v is set in the provider block.
+func configured(v types.String) bool { return !v.IsNull() && !v.IsUnknown() }
+
+// int64OrEnv returns the configured value, else the parsed environment variable. ok is false when
+// neither is set.
+func int64OrEnv(v types.Int64, env string) (n int64, ok bool, err error) {
+	if !v.IsNull() && !v.IsUnknown() { return v.ValueInt64(), true, nil }
+	s := strings.TrimSpace(os.Getenv(env))
+	if s == "" { return 0, false, nil }
+	n, err = strconv.ParseInt(s, 10, 64)
+	if err != nil { return 0, false, fmt.Errorf("%s=%q is not an integer", env, s) }
+	return n, true, nil
+}
+
+// boolOrEnv returns the configured value, else the parsed environment variable (1/0, true/false).
+func boolOrEnv(v types.Bool, env string) (b bool, ok bool, err error) {
+	if !v.IsNull() && !v.IsUnknown() { return v.ValueBool(), true, nil }
+	s := strings.TrimSpace(os.Getenv(env))
+	if s == "" { return false, false, nil }
+	b, err = strconv.ParseBool(s)
+	if err != nil { return false, false, fmt.Errorf("%s=%q is not a boolean", env, s) }
+	return b, true, nil
+}
+
+// listOrEnv returns the configured list, else the environment variable split on commas/whitespace.
+func listOrEnv(v []types.String, env string) []string {
+	var out []string
+	for _, s := range v { out = append(out, s.ValueString()) }
+	if len(out) > 0 { return out }
+	return strings.FieldsFunc(os.Getenv(env), func(r rune) bool { return r == ',' || r == ' ' })
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/provider"
 	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
@@ -35,14 +36,40 @@ type providerModel struct {
 	EnforcePolicyPaths  types.Bool   `tfsdk:"enforce_policy_paths"`
 	Strict              types.Bool   `tfsdk:"strict"`
 	Auth                *authModel   `tfsdk:"auth"`
+	TLS                 *tlsModel    `tfsdk:"tls"`
 	Defaults            *defaults    `tfsdk:"defaults"`
 	LogHTTP             types.Bool   `tfsdk:"log_http"`
+	MaxRetries          types.Int64  `tfsdk:"max_retries"`
+	RetryMaxWaitSeconds types.Int64  `tfsdk:"retry_max_wait_seconds"`
 }
 
 type authModel struct {
 	Method   types.String `tfsdk:"method"`
 	Username types.String `tfsdk:"username"`
 	Password types.String `tfsdk:"password"`
+	Realm    types.String `tfsdk:"realm"`
+	Krb5Conf types.String `tfsdk:"krb5_conf"`
+	Keytab   types.String `tfsdk:"keytab"`
+	CCache   types.String `tfsdk:"ccache"`
+
+	Token        types.String   `tfsdk:"token"`
+	TokenFile    types.String   `tfsdk:"token_file"`
+	TokenEnv     types.String   `tfsdk:"token_env"`
+	TokenURL     types.String   `tfsdk:"token_url"`
+	ClientID     types.String   `tfsdk:"client_id"`
+	ClientSecret types.String   `tfsdk:"client_secret"`
+	Scopes       []types.String `tfsdk:"scopes"`
+}
+
+type tlsModel struct {
+	CAFile             types.String   `tfsdk:"ca_file"`
+	CAPEM              types.String   `tfsdk:"ca_pem"`
+	ClientCert         types.String   `tfsdk:"client_cert"`
+	ClientKey          types.String   `tfsdk:"client_key"`
+	ServerName         types.String   `tfsdk:"server_name"`
+	MinVersion         types.String   `tfsdk:"min_version"`
+	InsecureSkipVerify types.Bool     `tfsdk:"insecure_skip_verify"`
+	PinnedSPKI         []types.String `tfsdk:"pinned_spki"`
 }
 
 type defaults struct {
@@ -60,19 +87,45 @@ func (p *HyperVApiV2Provider) Schema(_ context.Context, _ provider.SchemaRequest
 	resp.Schema = schema.Schema{
 		Description: "Policy-aware Terraform provider for Hyper-V Management API v2.",
 		Attributes: map[string]schema.Attribute{
-			"endpoint": schema.StringAttribute{Required: true, Description: "Base URL of the Hyper-V Management API v2."},
-			"proxy":    schema.StringAttribute{Optional: true, Description: "Optional HTTP proxy."},
-			"timeout_seconds": schema.Int64Attribute{Optional: true, Description: "Client timeout in seconds."},
+			"endpoint": schema.StringAttribute{Optional: true, Description: "Base URL of the Hyper-V Management API v2. Falls back to HYPERVAPI_ENDPOINT."},
+			"proxy":    schema.StringAttribute{Optional: true, Description: "Optional HTTP proxy. Falls back to HYPERVAPI_PROXY."},
+			"timeout_seconds": schema.Int64Attribute{Optional: true, Description: "Client timeout in seconds. Falls back to HYPERVAPI_TIMEOUT_SECONDS, then 300."},
 			"enforce_policy_paths": schema.BoolAttribute{Optional: true, Description: "Fail plan if explicit paths violate policy."},
-			"strict":               schema.BoolAttribute{Optional: true, Description: "Treat warnings as errors at plan-time."},
-			"log_http":             schema.BoolAttribute{Optional: true, Description: "Enable verbose HTTP request/response logs (debug level)."},
+			"strict":               schema.BoolAttribute{Optional: true, Description: "Treat policy warnings (plan-disk warnings, path violations, low free space, client fallbacks) as errors at plan-time."},
+			"log_http":             schema.BoolAttribute{Optional: true, Description: "Enable verbose HTTP request/response logs (debug level). Falls back to HYPERVAPI_LOG_HTTP."},
+			"max_retries":            schema.Int64Attribute{Optional: true, Description: "Retries for idempotent requests (GETs, start/stop, firmware) on transient failures. Default 3; 0 disables."},
+			"retry_max_wait_seconds": schema.Int64Attribute{Optional: true, Description: "Upper bound for a single retry backoff, including server Retry-After. Default 30."},
 		},
 		Blocks: map[string]schema.Block{
 			"auth": schema.SingleNestedBlock{
 				Attributes: map[string]schema.Attribute{
-					"method":   schema.StringAttribute{Required: true, Description: "Auth method: none | bearer | negotiate."},
-					"username": schema.StringAttribute{Optional: true},
-					"password": schema.StringAttribute{Optional: true, Sensitive: true},
+					"method":   schema.StringAttribute{Optional: true, Description: "Auth method: none | bearer | negotiate | ntlm. Falls back to HYPERVAPI_AUTH_METHOD."},
+					"username": schema.StringAttribute{Optional: true, Description: "Falls back to HYPERVAPI_USERNAME."},
+					"password": schema.StringAttribute{Optional: true, Sensitive: true, Description: "Falls back to HYPERVAPI_PASSWORD."},
+					"realm":     schema.StringAttribute{Optional: true, Description: "Kerberos realm for negotiate on non-Windows runners. Defaults to the user@REALM suffix or krb5.conf default_realm."},
+					"krb5_conf": schema.StringAttribute{Optional: true, Description: "Path to krb5.conf for negotiate on non-Windows runners. Defaults to KRB5_CONFIG or /etc/krb5.conf."},
+					"keytab":    schema.StringAttribute{Optional: true, Description: "Keytab for auth.username (non-Windows negotiate)."},
+					"ccache":    schema.StringAttribute{Optional: true, Description: "Kerberos credential cache (non-Windows negotiate). Defaults to KRB5CCNAME."},
+					"token":         schema.StringAttribute{Optional: true, Sensitive: true, Description: "Static bearer token."},
+					"token_file":    schema.StringAttribute{Optional: true, Description: "File holding a bearer token; re-read on refresh."},
+					"token_env":     schema.StringAttribute{Optional: true, Description: "Environment variable holding a bearer token."},
+					"token_url":     schema.StringAttribute{Optional: true, Description: "OAuth2 token endpoint for the client-credentials flow."},
+					"client_id":     schema.StringAttribute{Optional: true, Description: "OAuth2 client ID (with token_url)."},
+					"client_secret": schema.StringAttribute{Optional: true, Sensitive: true, Description: "OAuth2 client secret (with token_url)."},
+					"scopes":        schema.ListAttribute{ElementType: types.StringType, Optional: true, Description: "OAuth2 scopes (with token_url)."},
+				},
+			},
+			"tls": schema.SingleNestedBlock{
+				Description: "TLS settings applied to every auth method.",
+				Attributes: map[string]schema.Attribute{
+					"ca_file":              schema.StringAttribute{Optional: true, Description: "PEM bundle of additional trusted CAs."},
+					"ca_pem":               schema.StringAttribute{Optional: true, Description: "Inline PEM of additional trusted CAs."},
+					"client_cert":          schema.StringAttribute{Optional: true, Description: "Client certificate for mTLS (file path or PEM)."},
+					"client_key":           schema.StringAttribute{Optional: true, Sensitive: true, Description: "Client private key for mTLS (file path or PEM)."},
+					"server_name":          schema.StringAttribute{Optional: true, Description: "Override the server name used for SNI and verification."},
+					"min_version":          schema.StringAttribute{Optional: true, Description: "Minimum TLS version: 1.2 (default) | 1.3."},
+					"insecure_skip_verify": schema.BoolAttribute{Optional: true, Description: "Skip server certificate verification (lab use only)."},
+					"pinned_spki":          schema.ListAttribute{ElementType: types.StringType, Optional: true, Description: "Base64 SHA-256 SPKI pins; the server chain must match one."},
 				},
 			},
 			"defaults": schema.SingleNestedBlock{
@@ -93,27 +146,75 @@ func (p *HyperVApiV2Provider) Configure(ctx context.Context, req provider.Config
 		return
 	}
 
+	if data.Endpoint.IsUnknown() {
+		resp.Diagnostics.AddAttributeError(path.Root("endpoint"), "unknown endpoint", "The endpoint depends on values not known until apply; set it statically or via "+envEndpoint+".")
+		return
+	}
     cfg := client.Config{
-        Endpoint:           data.Endpoint.ValueString(),
-        Proxy:              data.Proxy.ValueString(),
+        Endpoint:           stringOrEnv(data.Endpoint, envEndpoint),
+        Proxy:              stringOrEnv(data.Proxy, envProxy),
         TimeoutSeconds:     300,
-        // Policy enforcement is owned by the API server; provider does not enforce.
-        EnforcePolicyPaths: false,
-        Strict:             false,
+        EnforcePolicyPaths: data.EnforcePolicyPaths.ValueBool(),
+        Strict:             data.Strict.ValueBool(),
         LogHTTP:            false,
     }
-	if !data.TimeoutSeconds.IsNull() && !data.TimeoutSeconds.IsUnknown() { cfg.TimeoutSeconds = int(data.TimeoutSeconds.ValueInt64()) }
-    // These settings are accepted for compatibility but are no-ops; API server enforces policy.
-    if !data.EnforcePolicyPaths.IsNull() && !data.EnforcePolicyPaths.IsUnknown() { _ = data.EnforcePolicyPaths.ValueBool() }
-    if !data.Strict.IsNull() && !data.Strict.IsUnknown() { _ = data.Strict.ValueBool() }
-	if data.Auth != nil {
-		cfg.Auth = client.AuthConfig{
-			Method:   data.Auth.Method.ValueString(),
-			Username: data.Auth.Username.ValueString(),
-			Password: data.Auth.Password.ValueString(),
+	if cfg.Endpoint == "" {
+		resp.Diagnostics.AddAttributeError(path.Root("endpoint"), "missing endpoint", "Set endpoint in the provider block or the "+envEndpoint+" environment variable.")
+		return
+	}
+	if n, ok, err := int64OrEnv(data.TimeoutSeconds, envTimeoutSeconds); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("timeout_seconds"), "invalid timeout_seconds", err.Error())
+	} else if ok {
+		cfg.TimeoutSeconds = int(n)
+	}
+	if b, ok, err := boolOrEnv(data.LogHTTP, envLogHTTP); err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("log_http"), "invalid log_http", err.Error())
+	} else if ok {
+		cfg.LogHTTP = b
+	}
+	// Auth settings fall back to the environment even when the auth block is omitted entirely
+	auth := data.Auth
+	if auth == nil { auth = &authModel{} }
+	// The token source env vars apply only when the block names no token source, so a configured
+	// token_url plus HYPERVAPI_TOKEN is not two sources
+	tokenSrc := stringOrEnv
+	if configured(auth.Token) || configured(auth.TokenFile) || configured(auth.TokenEnv) || configured(auth.TokenURL) {
+		tokenSrc = func(v types.String, _ string) string { return v.ValueString() }
+	}
+	cfg.Auth = client.AuthConfig{
+		Method:   stringOrEnv(auth.Method, envAuthMethod),
+		Username: stringOrEnv(auth.Username, envUsername),
+		Password: stringOrEnv(auth.Password, envPassword),
+		Realm:    auth.Realm.ValueString(),
+		Krb5Conf: auth.Krb5Conf.ValueString(),
+		Keytab:   auth.Keytab.ValueString(),
+		CCache:   auth.CCache.ValueString(),
+
+		Token:        tokenSrc(auth.Token, envToken),
+		TokenFile:    tokenSrc(auth.TokenFile, envTokenFile),
+		TokenEnv:     auth.TokenEnv.ValueString(),
+		TokenURL:     tokenSrc(auth.TokenURL, envTokenURL),
+		ClientID:     stringOrEnv(auth.ClientID, envClientID),
+		ClientSecret: stringOrEnv(auth.ClientSecret, envClientSecret),
+		Scopes:       listOrEnv(auth.Scopes, envScopes),
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	if data.TLS != nil {
+		cfg.TLS = &client.TLSConfig{
+			CAFile:             data.TLS.CAFile.ValueString(),
+			CAPEM:              data.TLS.CAPEM.ValueString(),
+			ClientCert:         data.TLS.ClientCert.ValueString(),
+			ClientKey:          data.TLS.ClientKey.ValueString(),
+			ServerName:         data.TLS.ServerName.ValueString(),
+			MinVersion:         data.TLS.MinVersion.ValueString(),
+			InsecureSkipVerify: data.TLS.InsecureSkipVerify.ValueBool(),
 		}
+		for _, p := range data.TLS.PinnedSPKI { cfg.TLS.PinnedSPKI = append(cfg.TLS.PinnedSPKI, p.ValueString()) }
 	}
-	if !data.LogHTTP.IsNull() && !data.LogHTTP.IsUnknown() { cfg.LogHTTP = data.LogHTTP.ValueBool() }
+	if !data.MaxRetries.IsNull() && !data.MaxRetries.IsUnknown() { n := int(data.MaxRetries.ValueInt64()); cfg.MaxRetries = &n }
+	if !data.RetryMaxWaitSeconds.IsNull() && !data.RetryMaxWaitSeconds.IsUnknown() { cfg.RetryMaxWaitSeconds = int(data.RetryMaxWaitSeconds.ValueInt64()) }
 	if data.Defaults != nil {
 		cfg.Defaults = &client.Defaults{
 			CPU:    int(data.Defaults.CPU.ValueInt64()),
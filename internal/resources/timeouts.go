@@ -0,0 +1,54 @@
+package resources
+
+import (
+    "context"
+    "errors"
+    "time"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+)
+
+const (
+    defaultCreateTimeout = 30 * time.Minute
+    defaultUpdateTimeout = 30 * time.Minute
+    defaultDeleteTimeout = 10 * time.Minute
+    defaultReadTimeout   = 5 * time.Minute
+)
+
+// nullTimeouts is an unset timeouts block for state built outside a plan, e.g. on import.
+func nullTimeouts() timeouts.Value {
+    t := map[string]attr.Type{"create": types.StringType, "update": types.StringType, "delete": types.StringType, "read": types.StringType}
+    return timeouts.Value{Object: types.ObjectNull(t)}
+}
+
+// opDeadline bounds one CRUD operation by its timeouts value and remembers the step in progress,
+// so an expired deadline is reported as "timed out while <step>".
+type opDeadline struct {
+    op    string // create | update | delete | read
+    limit time.Duration
+    step  string
+}
+
+func withDeadline(ctx context.Context, op string, limit time.Duration) (context.Context, context.CancelFunc, *opDeadline) {
+    ctx, cancel := context.WithTimeout(ctx, limit)
+    return ctx, cancel, &opDeadline{op: op, limit: limit}
+}
+
+func (o *opDeadline) at(step string) { o.step = step }
+
+// report adds the timeout error once ctx has expired, whatever the failing call reported.
+func (o *opDeadline) report(ctx context.Context, diags *diag.Diagnostics) {
+    if !errors.Is(ctx.Err(), context.DeadlineExceeded) { return }
+    step := o.step
+    if step == "" { step = o.op + " of the VM" }
+    diags.AddError("timed out while "+step,
+        "The "+o.op+" timeout ("+o.limit.String()+") expired. Raise timeouts."+o.op+" in the resource if the host needs longer.")
+}
+
+// cleanupContext outlives an expired operation deadline so compensation and restarts still run.
+func cleanupContext(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+    return context.WithTimeout(context.WithoutCancel(ctx), d)
+}
@@ -7,11 +7,15 @@ import (
     "strings"
     "time"
 
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/resource"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
 	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-framework-timeouts/resource/timeouts"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 
     "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
 )
@@ -30,22 +34,39 @@ type vmModel struct {
     Power   types.String `tfsdk:"power"`
     StopMethod types.String `tfsdk:"stop_method"`
     WaitTimeoutSec types.Int64 `tfsdk:"wait_timeout_seconds"`
+    ShutdownTimeoutSec types.Int64 `tfsdk:"shutdown_timeout_seconds"`
     Generation types.Int64  `tfsdk:"generation"`
     SwitchName types.String `tfsdk:"switch_name"`
     NewVhdPath types.String `tfsdk:"new_vhd_path"`
     NewVhdSizeGB types.Int64 `tfsdk:"new_vhd_size_gb"`
     VhdType types.String `tfsdk:"vhd_type"`
     ParentPath types.String `tfsdk:"parent_path"`
+    OnCreateFailure types.String `tfsdk:"on_create_failure"`
 
+    DynamicMemory *dynamicMemoryModel `tfsdk:"dynamic_memory"`
     Firmware *firmwareModel `tfsdk:"firmware"`
     Security *securityModel `tfsdk:"security"`
     Lifecycle *lifecycleModel `tfsdk:"vm_lifecycle"`
     Disks    []diskModel `tfsdk:"disk"`
+    NetworkInterfaces []networkInterfaceModel `tfsdk:"network_interface"`
+    Timeouts timeouts.Value `tfsdk:"timeouts"`
+}
+
+type dynamicMemoryModel struct {
+    Enabled       types.Bool   `tfsdk:"enabled"`
+    Minimum       types.String `tfsdk:"minimum"`
+    Maximum       types.String `tfsdk:"maximum"`
+    BufferPercent types.Int64  `tfsdk:"buffer_percent"`
+    Priority      types.Int64  `tfsdk:"priority"`
 }
 
 type firmwareModel struct {
     SecureBoot         types.Bool   `tfsdk:"secure_boot"`
     SecureBootTemplate types.String `tfsdk:"secure_boot_template"`
+    BootOrder          []types.String `tfsdk:"boot_order"`
+    BootDevice         types.String `tfsdk:"boot_device"`
+    NetworkBootProtocol types.String `tfsdk:"preferred_network_boot_protocol"`
+    PauseAfterBootFailure types.Bool `tfsdk:"pause_after_boot_failure"`
 }
 
 type securityModel struct {
@@ -71,10 +92,19 @@ type diskModel struct {
     AutoAttach  types.Bool   `tfsdk:"auto_attach"`
     Protect     types.Bool   `tfsdk:"protect"`
     Controller  types.String `tfsdk:"controller"`
+    ControllerNumber types.Int64 `tfsdk:"controller_number"`
     Lun         types.Int64  `tfsdk:"lun"`
     Placement   *placementModel `tfsdk:"placement"`
 }
 
+type networkInterfaceModel struct {
+    Name        types.String `tfsdk:"name"`
+    Switch      types.String `tfsdk:"switch"`
+    MacAddress  types.String `tfsdk:"mac_address"`
+    IsConnected types.Bool   `tfsdk:"is_connected"`
+    VlanID      types.Int64  `tfsdk:"vlan_id"`
+}
+
 type placementModel struct {
     PreferRoot   types.String `tfsdk:"prefer_root"`
     MinFreeGB    types.Int64  `tfsdk:"min_free_gb"`
@@ -85,22 +115,24 @@ func (r *VMResource) Metadata(_ context.Context, _ resource.MetadataRequest, res
 	resp.TypeName = "hypervapiv2_vm"
 }
 
-func (r *VMResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
+func (r *VMResource) Schema(ctx context.Context, _ resource.SchemaRequest, resp *resource.SchemaResponse) {
     resp.Schema = schema.Schema{
         Attributes: map[string]schema.Attribute{
             "id":     schema.StringAttribute{Computed: true},
             "name":   schema.StringAttribute{Required: true, PlanModifiers: []planmodifier.String{stringplanmodifier.RequiresReplace()}},
-            "cpu":    schema.Int64Attribute{Optional: true},
-            "memory": schema.StringAttribute{Optional: true},
-            "power":  schema.StringAttribute{Optional: true, Description: "running | stopped"},
-            "stop_method": schema.StringAttribute{Optional: true, Description: "graceful | force | turnoff"},
+            "cpu":    schema.Int64Attribute{Optional: true, Computed: true, Description: "vCPU count; defaults to provider defaults.cpu"},
+            "memory": schema.StringAttribute{Optional: true, Computed: true, Description: "Startup memory, e.g. 2GB; defaults to provider defaults.memory"},
+            "power":  schema.StringAttribute{Optional: true, Description: "running | stopped | saved | paused"},
+            "stop_method": schema.StringAttribute{Optional: true, Description: "graceful (default) | force | turnoff | escalate (graceful, then force, then turnoff)"},
             "wait_timeout_seconds": schema.Int64Attribute{Optional: true, Description: "Timeout for power transitions"},
+            "shutdown_timeout_seconds": schema.Int64Attribute{Optional: true, Description: "With stop_method = escalate, how long each step may take before the next, default 60"},
             "generation": schema.Int64Attribute{Optional: true, Description: "VM generation (1 or 2), default 2"},
-            "switch_name": schema.StringAttribute{Optional: true},
+            "switch_name": schema.StringAttribute{Optional: true, Description: "Switch for a single default adapter; use network_interface blocks for more control"},
             "new_vhd_path": schema.StringAttribute{Optional: true, Description: "Path for new OS VHD to create and attach"},
             "new_vhd_size_gb": schema.Int64Attribute{Optional: true, Description: "Size of the new OS VHD in GB"},
             "vhd_type": schema.StringAttribute{Optional: true, Description: "VHD type: Dynamic (default), Fixed, or Differencing"},
             "parent_path": schema.StringAttribute{Optional: true, Description: "Parent VHD path (required when vhd_type is Differencing)"},
+            "on_create_failure": schema.StringAttribute{Optional: true, Description: "rollback (default) deletes a partially created VM and its new disks; keep saves it as tainted"},
         },
         Blocks: map[string]schema.Block{
             "disk": schema.ListNestedBlock{
@@ -109,17 +141,18 @@ func (r *VMResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *r
                         "name":        schema.StringAttribute{Optional: true},
                         "purpose":     schema.StringAttribute{Optional: true},
                         "boot":        schema.BoolAttribute{Optional: true},
-                        "size":        schema.StringAttribute{Optional: true},
+                        "size":        schema.StringAttribute{Optional: true, Computed: true, Description: "Disk size, e.g. 40GB; the OS disk defaults to provider defaults.disk"},
                         "type":        schema.StringAttribute{Optional: true},
-                        "path":        schema.StringAttribute{Optional: true},
+                        "path":        schema.StringAttribute{Optional: true, Computed: true, Description: "VHD path; auto-placed via plan-disk when unset and recorded in state"},
                         "clone_from":  schema.StringAttribute{Optional: true},
                         "source_path": schema.StringAttribute{Optional: true},
                         "parent_path": schema.StringAttribute{Optional: true, Description: "Parent VHD path for differencing disks"},
                         "read_only":   schema.BoolAttribute{Optional: true},
                         "auto_attach": schema.BoolAttribute{Optional: true},
-                        "protect":     schema.BoolAttribute{Optional: true},
-                        "controller":  schema.StringAttribute{Optional: true},
-                        "lun":         schema.Int64Attribute{Optional: true},
+                        "protect":     schema.BoolAttribute{Optional: true, Computed: true, Description: "Never delete this disk file; defaults to false, true for imported disks"},
+                        "controller":  schema.StringAttribute{Optional: true, Computed: true, Description: "scsi | ide (Generation 1 only); defaults to the generation's default controller"},
+                        "controller_number": schema.Int64Attribute{Optional: true, Computed: true, Description: "Controller number: 0-3 for SCSI, 0-1 for IDE"},
+                        "lun":         schema.Int64Attribute{Optional: true, Computed: true, Description: "Location on the controller: 0-63 for SCSI, 0-1 for IDE"},
                     },
                     Blocks: map[string]schema.Block{
                         "placement": schema.SingleNestedBlock{
@@ -132,10 +165,34 @@ func (r *VMResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *r
                     },
                 },
             },
+            "network_interface": schema.ListNestedBlock{
+                NestedObject: schema.NestedBlockObject{
+                    Attributes: map[string]schema.Attribute{
+                        "name":         schema.StringAttribute{Optional: true, Computed: true, Description: "Adapter name; defaults to nic<index>"},
+                        "switch":       schema.StringAttribute{Required: true},
+                        "mac_address":  schema.StringAttribute{Optional: true, Computed: true, Description: "Static MAC; the dynamically assigned MAC is recorded when unset"},
+                        "is_connected": schema.BoolAttribute{Optional: true, Computed: true, Description: "Connect the adapter to switch; defaults to true"},
+                        "vlan_id":      schema.Int64Attribute{Optional: true, Description: "Access VLAN ID (1-4094); untagged when unset"},
+                    },
+                },
+            },
+            "dynamic_memory": schema.SingleNestedBlock{
+                Attributes: map[string]schema.Attribute{
+                    "enabled":        schema.BoolAttribute{Optional: true, Computed: true, Description: "Defaults to true when the block is present"},
+                    "minimum":        schema.StringAttribute{Optional: true, Description: "Minimum memory, e.g. 512MB; at most memory"},
+                    "maximum":        schema.StringAttribute{Optional: true, Description: "Maximum memory, e.g. 8GB; at least memory"},
+                    "buffer_percent": schema.Int64Attribute{Optional: true, Description: "Memory buffer (5-2000)"},
+                    "priority":       schema.Int64Attribute{Optional: true, Description: "Memory weight (0-100)"},
+                },
+            },
             "firmware": schema.SingleNestedBlock{
                 Attributes: map[string]schema.Attribute{
                     "secure_boot":          schema.BoolAttribute{Optional: true},
                     "secure_boot_template": schema.StringAttribute{Optional: true},
+                    "boot_order":           schema.ListAttribute{Optional: true, ElementType: types.StringType, Description: "Boot devices in order: disk[:<disk name or path>], cd, network[:<adapter name>]"},
+                    "boot_device":          schema.StringAttribute{Optional: true, Description: "First boot device, same syntax as boot_order; conflicts with boot_order"},
+                    "preferred_network_boot_protocol": schema.StringAttribute{Optional: true, Description: "IPv4 | IPv6"},
+                    "pause_after_boot_failure":        schema.BoolAttribute{Optional: true},
                 },
             },
             "security": schema.SingleNestedBlock{
@@ -144,6 +201,7 @@ func (r *VMResource) Schema(_ context.Context, _ resource.SchemaRequest, resp *r
                     "encrypt": schema.BoolAttribute{Optional: true},
                 },
             },
+            "timeouts": timeouts.Block(ctx, timeouts.Opts{Create: true, Update: true, Delete: true, Read: true}),
             "vm_lifecycle": schema.SingleNestedBlock{
                 Attributes: map[string]schema.Attribute{
                     "delete_disks": schema.BoolAttribute{Optional: true, Description: "Delete provider-created VHDX files on destroy when true"},
@@ -166,10 +224,15 @@ func (r *VMResource) Create(ctx context.Context, req resource.CreateRequest, res
 		resp.Diagnostics.AddError("provider not configured", "client missing")
 		return
 	}
+	limit, d := data.Timeouts.Create(ctx, defaultCreateTimeout)
+	resp.Diagnostics.Append(d...)
+	if resp.Diagnostics.HasError() { return }
+	ctx, cancel, op := withDeadline(ctx, "create", limit)
+	defer cancel()
 
 	// Build API request
 	var cpuPtr *int
-	if !data.CPU.IsNull() { c := int(data.CPU.ValueInt64()); cpuPtr = &c }
+	if !data.CPU.IsNull() && !data.CPU.IsUnknown() { c := int(data.CPU.ValueInt64()); cpuPtr = &c }
 	var memPtr *int
 	if !data.Memory.IsNull() && data.Memory.ValueString() != "" {
 		if mb, ok := toMB(data.Memory.ValueString()); ok { memPtr = &mb }
@@ -178,109 +241,55 @@ func (r *VMResource) Create(ctx context.Context, req resource.CreateRequest, res
 	if !data.Generation.IsNull() && data.Generation.ValueInt64() > 0 { gen = int(data.Generation.ValueInt64()) }
 	var sw *string
 	if !data.SwitchName.IsNull() && data.SwitchName.ValueString() != "" { s := data.SwitchName.ValueString(); sw = &s }
+	// network_interface blocks replace the default adapter switch_name would create
+	if len(data.NetworkInterfaces) > 0 { sw = nil }
     var vhdPath *string
     var vhdSize *int
     if !data.NewVhdPath.IsNull() && data.NewVhdPath.ValueString() != "" { p := data.NewVhdPath.ValueString(); vhdPath = &p }
     if !data.NewVhdSizeGB.IsNull() && data.NewVhdSizeGB.ValueInt64() > 0 { sz := int(data.NewVhdSizeGB.ValueInt64()); vhdSize = &sz }
 
-    // Unified disk block: prefer disk{} over legacy new_vhd_* when provided
+    tflog.Debug(ctx, "create begin", map[string]any{"vm": data.Name.ValueString(), "cpu": data.CPU.String(), "memory": data.Memory.ValueString(),
+        "switch": data.SwitchName.ValueString(), "disks": len(data.Disks), "nics": len(data.NetworkInterfaces)})
+
+    // Any failure from here on is compensated per on_create_failure
+    progress := &createProgress{}
+    defer func() {
+        if !resp.Diagnostics.HasError() { return }
+        cctx, ccancel := cleanupContext(ctx, 5*time.Minute)
+        defer ccancel()
+        r.createFailed(cctx, &data, progress, resp)
+    }()
+    // Runs before the compensation above, so a deadline always counts as a failure
+    defer op.report(ctx, &resp.Diagnostics)
+
+    // Unified disk block: prefer disk{} over legacy new_vhd_* when provided. Every disk is resolved
+    // and cloned up front; the OS disk is created with the VM and the rest are attached after.
+    var jobs []*diskJob
+    osIdx := osDiskIndex(data.Disks)
     if len(data.Disks) > 0 {
-        var chosen *diskModel
-        for i := range data.Disks {
-            d := &data.Disks[i]
-            // pick OS/boot disk first
-            if (!d.Boot.IsNull() && d.Boot.ValueBool()) || (!d.Purpose.IsNull() && strings.EqualFold(d.Purpose.ValueString(), "os")) {
-                chosen = d; break
-            }
-        }
-        if chosen == nil { chosen = &data.Disks[0] }
-        // Detect scenario
-        hasClone := (!chosen.CloneFrom.IsNull() && chosen.CloneFrom.ValueString() != "")
-        hasAttach := (!chosen.SourcePath.IsNull() && chosen.SourcePath.ValueString() != "")
-        // Size in GB required for new; path optional
-        var szGB *int
-        if !chosen.Size.IsNull() && chosen.Size.ValueString() != "" {
-            // parse like "20GB" or MB
-            if mb, ok := toMB(chosen.Size.ValueString()); ok {
-        // Progress: summarize planned create
-        {
-            n := data.Name.ValueString()
-            cpu := ""
-            if !data.CPU.IsNull() { cpu = strconv.FormatInt(data.CPU.ValueInt64(), 10) }
-            mem := ""
-            if !data.Memory.IsNull() { mem = data.Memory.ValueString() }
-            sw := ""
-            if !data.SwitchName.IsNull() { sw = data.SwitchName.ValueString() }
-            resp.Diagnostics.AddWarning("create begin", "vm="+n+" cpu="+cpu+" mem="+mem+" switch="+sw)
-        }
-                g := mb / 1024
-                if g <= 0 { g = 1 }
-                szGB = &g
-            }
-        }
-        var pathStr *string
-        if !chosen.Path.IsNull() && chosen.Path.ValueString() != "" {
-            p := chosen.Path.ValueString(); pathStr = &p
-        }
-        if !hasClone && !hasAttach {
-            if szGB != nil { vhdSize = szGB }
-            if pathStr != nil { vhdPath = pathStr }
-        }
-        // If no explicit path, call PlanDisk for auto placement
-        if vhdPath == nil && !hasClone && !hasAttach {
-            purpose := "os"
-            if !chosen.Purpose.IsNull() && chosen.Purpose.ValueString() != "" { purpose = chosen.Purpose.ValueString() }
-            req := client.DiskPlanRequest{ VMName: data.Name.ValueString(), Operation: "create", Purpose: purpose }
-            if vhdSize != nil { req.SizeGB = vhdSize }
-            // Placement hints
-            if chosen.Placement != nil {
-                if !chosen.Placement.PreferRoot.IsNull() && chosen.Placement.PreferRoot.ValueString() != "" { pr := chosen.Placement.PreferRoot.ValueString(); req.PreferRoot = &pr }
-                if !chosen.Placement.CoLocateWith.IsNull() && chosen.Placement.CoLocateWith.ValueString() != "" { cw := chosen.Placement.CoLocateWith.ValueString(); req.CoLocateWith = &cw }
-                if !chosen.Placement.MinFreeGB.IsNull() && chosen.Placement.MinFreeGB.ValueInt64() > 0 { mf := int(chosen.Placement.MinFreeGB.ValueInt64()); req.MinFreeGB = &mf }
-            }
-            if out, perr := r.cl.PlanDisk(ctx, req); perr == nil && out != nil && out.Path != "" {
-                p := out.Path; vhdPath = &p
-            } else if perr != nil {
-                resp.Diagnostics.AddWarning("disk auto-placement failed", perr.Error())
-            }
-        }
-        // Handle clone scenario
-        if hasClone {
-            src := chosen.CloneFrom.ValueString()
-            var target string
-            if !chosen.Path.IsNull() && chosen.Path.ValueString() != "" {
-                target = chosen.Path.ValueString()
-            } else {
-                req := client.DiskPlanRequest{ VMName: data.Name.ValueString(), Operation: "clone", Purpose: "os" }
-                req.CloneFrom = &src
-                if out, perr := r.cl.PlanDisk(ctx, req); perr == nil && out != nil && out.Path != "" {
-                    target = out.Path
-                } else if perr != nil {
-                    resp.Diagnostics.AddError("clone plan failed", perr.Error()); return
-                }
-            }
-            resp.Diagnostics.AddWarning("clone enqueue", "from="+src+" to="+target)
-            prep, perr := r.cl.ClonePrepare(ctx, client.ClonePrepareRequest{ SourcePath: src, TargetPath: &target })
-            if perr != nil { resp.Diagnostics.AddError("clone prepare failed", perr.Error()); return }
-            id, qerr := r.cl.CloneEnqueue(ctx, prep.Token)
-            if qerr != nil { resp.Diagnostics.AddError("clone enqueue failed", qerr.Error()); return }
-            deadline := time.Now().Add(15 * time.Minute)
-            for time.Now().Before(deadline) {
-                if t, terr := r.cl.GetCloneTask(ctx, id); terr == nil && t != nil {
-                    st := strings.ToLower(t.Status)
-                    if st == "succeeded" || st == "success" || st == "completed" || st == "done" { vhdPath = &target; break }
-                    if st == "failed" {
-                        var msg string
-                        if t.Error != nil { msg = *t.Error }
-                        if msg == "" { msg = "clone failed" }
-                        resp.Diagnostics.AddError("clone failed", msg)
-                        return
-                    }
-                }
-                time.Sleep(1 * time.Second)
-            }
-            if vhdPath == nil { resp.Diagnostics.AddError("clone timeout", "clone did not complete within timeout"); return }
-            resp.Diagnostics.AddWarning("clone complete", "target="+target)
+        op.at("planning disk placement")
+        jobs = r.resolveDisks(ctx, &data, nil, &resp.Diagnostics)
+        if resp.Diagnostics.HasError() { return }
+        progress.jobs = jobs
+        op.at("cloning disks")
+        r.runClones(ctx, &data, jobs, &resp.Diagnostics)
+        if resp.Diagnostics.HasError() { return }
+        switch osj := jobs[osIdx]; {
+        case osj.slot != nil && osj.path != "":
+            // CreateVm cannot place a disk; attach it at the requested location instead
+            osIdx = -1
+        case osj.slot != nil && osj.kind != diskAttach:
+            resp.Diagnostics.AddAttributeError(path.Root("disk").AtListIndex(osIdx), "disk location needs a path",
+                "controller and lun can only be honored when the OS disk path is known; set disk.path or fix plan-disk.")
+            return
+        case osj.kind == diskNew:
+            if osj.sizeGB != nil { vhdSize = osj.sizeGB }
+            if osj.path != "" { p := osj.path; vhdPath = &p }
+        case osj.kind == diskClone:
+            p := osj.path; vhdPath = &p
+        case osj.kind == diskAttach:
+            // Attached after create like the other disks
+            osIdx = -1
         }
     }
 
@@ -294,6 +303,11 @@ func (r *VMResource) Create(ctx context.Context, req resource.CreateRequest, res
         p := data.ParentPath.ValueString()
         parentPathPtr = &p
     }
+    // Per-disk type/parent win over the legacy top-level settings for a new OS disk
+    if osIdx >= 0 && jobs[osIdx].kind == diskNew {
+        if jobs[osIdx].vhdType != nil { vhdTypePtr = jobs[osIdx].vhdType }
+        if jobs[osIdx].parent != nil { parentPathPtr = jobs[osIdx].parent }
+    }
 
     reqBody := client.CreateVmRequest{
         Name:         data.Name.ValueString(),
@@ -306,111 +320,72 @@ func (r *VMResource) Create(ctx context.Context, req resource.CreateRequest, res
         VhdType:      vhdTypePtr,
         ParentPath:   parentPathPtr,
     }
-    resp.Diagnostics.AddWarning("createvm request", "name="+reqBody.Name)
+    tflog.Debug(ctx, "createvm request", map[string]any{"vm": reqBody.Name})
+    op.at("creating the VM")
+    // Only a definite 404 proves the name is free; any other answer counts as an existing VM
+    _, gerr := r.cl.GetVm(ctx, reqBody.Name)
+    existed := !client.IsNotFound(gerr)
     out, err := r.cl.CreateVm(ctx, reqBody)
     if err != nil {
-        resp.Diagnostics.AddError("create failed", err.Error())
+        // A failed create can still leave the VM behind; never claim one that was already there,
+        // and a conflict means the name belongs to someone else
+        if _, gerr := r.cl.GetVm(ctx, reqBody.Name); gerr == nil && !existed && !client.IsConflict(err) { progress.vm = true }
+        resp.Diagnostics.AddError("create failed", client.Detail(err))
         return
     }
-    resp.Diagnostics.AddWarning("createvm ok", reqBody.Name)
+    progress.vm = true
+    if out != nil { progress.vmID = out.VmId }
+    if osIdx >= 0 { jobs[osIdx].created = true }
+    if len(jobs) == 0 { progress.osFile = vhdPath }
+    tflog.Debug(ctx, "createvm ok", map[string]any{"vm": reqBody.Name})
     if out != nil && out.Message != "" {
         resp.Diagnostics.AddWarning("server", out.Message)
     }
 
-    // If attach requested, perform attach after VM creation
-    if len(data.Disks) > 0 {
-        var chosen *diskModel
-        for i := range data.Disks {
-            d := &data.Disks[i]
-            if (!d.Boot.IsNull() && d.Boot.ValueBool()) || (!d.Purpose.IsNull() && strings.EqualFold(d.Purpose.ValueString(), "os")) { chosen = d; break }
-        }
-        if chosen == nil { chosen = &data.Disks[0] }
-        hasAttach := (!chosen.SourcePath.IsNull() && chosen.SourcePath.ValueString() != "")
-        if hasAttach {
-            ro := false
-            if !chosen.ReadOnly.IsNull() { ro = chosen.ReadOnly.ValueBool() }
-
-            // Parse VHD parameters for attach
-            var attachVhdSize *int
-            var attachVhdType *string
-            var attachParentPath *string
-
-            if !chosen.Size.IsNull() && chosen.Size.ValueString() != "" {
-                if mb, ok := toMB(chosen.Size.ValueString()); ok {
-                    g := mb / 1024
-                    if g > 0 { attachVhdSize = &g }
-                }
-            }
-            if !chosen.Type.IsNull() && chosen.Type.ValueString() != "" {
-                t := chosen.Type.ValueString()
-                attachVhdType = &t
-            }
-            if !chosen.ParentPath.IsNull() && chosen.ParentPath.ValueString() != "" {
-                p := chosen.ParentPath.ValueString()
-                attachParentPath = &p
-            }
-
-            if err := r.cl.AttachDisk(ctx, data.Name.ValueString(), chosen.SourcePath.ValueString(), ro, attachVhdSize, attachVhdType, attachParentPath); err != nil {
-                resp.Diagnostics.AddError("attach failed", err.Error()); return
-            }
-        }
-    }
-
-    // Post-create: apply firmware/security if requested
-    if data.Firmware != nil {
-        // secure boot
-        if !data.Firmware.SecureBoot.IsNull() {
-            enabled := data.Firmware.SecureBoot.ValueBool()
-            tmpl := ""
-            if !data.Firmware.SecureBootTemplate.IsNull() { tmpl = data.Firmware.SecureBootTemplate.ValueString() }
-            resp.Diagnostics.AddWarning("firmware", "secure_boot="+strconv.FormatBool(enabled)+" template="+tmpl)
-            if err := r.cl.SetSecureBoot(ctx, reqBody.Name, enabled, tmpl); err != nil {
-                resp.Diagnostics.AddError("firmware secure-boot", err.Error()); return
-            }
-            // Align boot order for convenience
-            if err := r.cl.SetFirstBootToPrimaryDisk(ctx, reqBody.Name); err != nil {
-                resp.Diagnostics.AddError("firmware first-boot", err.Error()); return
-            }
-        }
+    // Attach the remaining disks (data disks, clones, and existing files)
+    if len(jobs) > 0 {
+        op.at("attaching disks")
+        r.attachDisks(ctx, reqBody.Name, jobs, osIdx, &resp.Diagnostics)
+        if resp.Diagnostics.HasError() { return }
+        r.recordDiskPaths(ctx, &data, jobs)
     }
-    if data.Security != nil {
-        // TODO: wire TPM/encrypt when API mapping is finalized in client
+    owned := &diskOwnership{}
+    owned.record(jobs)
+    if len(jobs) == 0 && vhdPath != nil { owned.Created = appendPath(owned.Created, *vhdPath) }
+    writeOwnership(ctx, resp.Private, owned, &resp.Diagnostics)
+    if len(data.NetworkInterfaces) > 0 {
+        all := make([]int, len(data.NetworkInterfaces))
+        for i := range all { all[i] = i }
+        op.at("adding network adapters")
+        r.addNics(ctx, reqBody.Name, &data, all, &resp.Diagnostics)
+        if resp.Diagnostics.HasError() { return }
     }
 
-    // Verify configuration (CPU/Memory) matches plan; poll briefly to account for eventual consistency
-    // Reuse wait_timeout_seconds if provided, else default to a short window
-    verifyWindowSec := 20
-    if !data.WaitTimeoutSec.IsNull() {
-        if v := int(data.WaitTimeoutSec.ValueInt64()); v > 0 { verifyWindowSec = v }
-    }
-    if verifyWindowSec > 120 { verifyWindowSec = 120 } // cap to avoid long blocks
-    verifyDeadline := time.Now().Add(time.Duration(verifyWindowSec) * time.Second)
-    for time.Now().Before(verifyDeadline) {
-        okCpu := true
-        okMem := true
-        if cpuPtr != nil {
-            if pc, perr := r.cl.GetVmProcessorConfig(ctx, reqBody.Name); perr == nil {
-                okCpu = (pc.Count == *cpuPtr)
-            }
-        }
-        if memPtr != nil {
-            if mc, merr := r.cl.GetVmMemoryConfig(ctx, reqBody.Name); merr == nil {
-                okMem = (mc.StartupMB == *memPtr)
-            }
-        }
-        if okCpu && okMem { break }
-        time.Sleep(1000 * time.Millisecond)
-    }
-    // Final check; if mismatch, emit warnings but do not block creation
-    if cpuPtr != nil {
-        if pc, perr := r.cl.GetVmProcessorConfig(ctx, reqBody.Name); perr == nil && pc.Count != *cpuPtr {
-            resp.Diagnostics.AddWarning("vm cpu mismatch", "Server reports CPU count="+strconv.Itoa(pc.Count)+", expected="+strconv.Itoa(*cpuPtr))
+    if data.DynamicMemory != nil {
+        op.at("configuring dynamic memory")
+        var mreq client.SetVmMemoryRequest
+        dynamicRequest(data.DynamicMemory, &mreq)
+        if err := r.cl.SetVmMemory(ctx, reqBody.Name, mreq); err != nil {
+            resp.Diagnostics.AddAttributeError(path.Root("dynamic_memory"), "dynamic memory failed", client.Detail(err))
+            return
         }
     }
-    if memPtr != nil {
-        if mc, merr := r.cl.GetVmMemoryConfig(ctx, reqBody.Name); merr == nil && mc.StartupMB != *memPtr {
-            resp.Diagnostics.AddWarning("vm memory mismatch", "Server reports startupMB="+strconv.Itoa(mc.StartupMB)+", expected="+strconv.Itoa(*memPtr))
-        }
+
+    // Post-create: apply firmware/security if requested
+    op.at("configuring firmware")
+    r.applyFirmware(ctx, nil, &data, nil, &resp.Diagnostics)
+    if resp.Diagnostics.HasError() { return }
+    // The VM is still off, which vTPM and encryption changes require
+    op.at("configuring security")
+    tpm, encrypt := securityChanges(&data, nil)
+    r.applySecurity(ctx, reqBody.Name, tpm, encrypt, &resp.Diagnostics)
+    if resp.Diagnostics.HasError() { return }
+
+    // Verify configuration (CPU/Memory) matches plan; the host applies settings asynchronously.
+    // A mismatch is reported but does not block creation.
+    op.at("verifying cpu and memory")
+    if err := r.waitForConfig(ctx, reqBody.Name, cpuPtr, memPtr, verifyWindow(&data)); err != nil {
+        resp.Diagnostics.AddWarning("vm config mismatch", err.Error())
     }
 
     // Map minimal fields back; API returns CommandResult envelope
@@ -421,74 +396,40 @@ func (r *VMResource) Create(ctx context.Context, req resource.CreateRequest, res
         data.ID = types.StringValue(reqBody.Name)
     }
     data.Name = types.StringValue(reqBody.Name)
-    if cpuPtr != nil { data.CPU = types.Int64Value(int64(*cpuPtr)) }
+    if cpuPtr != nil {
+        data.CPU = types.Int64Value(int64(*cpuPtr))
+    } else if pc, perr := r.cl.GetVmProcessorConfig(ctx, reqBody.Name); perr == nil {
+        // No cpu and no provider default: record what the host chose
+        data.CPU = types.Int64Value(int64(pc.Count))
+    } else {
+        data.CPU = types.Int64Null()
+    }
     // Preserve exactly what user set to avoid post-apply drift errors
-    if !data.Memory.IsNull() && data.Memory.ValueString() != "" {
+    if !data.Memory.IsNull() && !data.Memory.IsUnknown() && data.Memory.ValueString() != "" {
         // keep as provided (e.g., "2GB")
     } else if memPtr != nil {
         // if not provided but we inferred, set canonical MB string
         data.Memory = types.StringValue(strconv.Itoa(*memPtr) + "MB")
+    } else if mc, merr := r.cl.GetVmMemoryConfig(ctx, reqBody.Name); merr == nil && mc.StartupMB > 0 {
+        data.Memory = types.StringValue(strconv.Itoa(mc.StartupMB) + "MB")
+    } else {
+        data.Memory = types.StringNull()
     }
-
     // Handle desired power state
-    _ = r.applyDesiredPower(ctx, &data)
+    op.at("waiting for power state " + data.Power.ValueString())
+    if err := r.applyDesiredPower(ctx, &data); err != nil {
+        r.recordPower(ctx, &data)
+        resp.Diagnostics.AddAttributeError(path.Root("power"), "power change failed", client.Detail(err))
+        return
+    }
+    op.at("reading network adapters")
+    // Dynamic MACs are assigned at first start, so read them back after power
+    r.recordNics(ctx, &data, &resp.Diagnostics)
+    settleUnknowns(&data)
 
     resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
 }
 
-func (r *VMResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
-	var data vmModel
-	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
-	if resp.Diagnostics.HasError() { return }
-	if r.cl == nil {
-		resp.Diagnostics.AddError("provider not configured", "client missing")
-		return
-	}
-	if data.Name.IsNull() || data.Name.ValueString() == "" {
-		resp.State.RemoveResource(ctx)
-		return
-	}
-	_, status, err := r.cl.GetVm(ctx, data.Name.ValueString())
-	if err != nil {
-		// If 404, drop from state; otherwise report error
-		if status == 404 {
-			resp.State.RemoveResource(ctx)
-			return
-		}
-		// Best-effort heuristic: treat not-found-ish errors as removed
-		if err != nil {
-			e := strings.ToLower(err.Error())
-			if strings.Contains(e, "not found") || strings.Contains(e, "does not exist") || strings.Contains(e, "objectnotfound") {
-				resp.State.RemoveResource(ctx)
-				return
-			}
-		}
-		resp.Diagnostics.AddError("read failed", err.Error())
-		return
-	}
-	// Keep existing state attributes; future: map server fields into state
-	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
-}
-
-func (r *VMResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
-    var plan vmModel
-    var state vmModel
-    resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
-    resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
-    if resp.Diagnostics.HasError() { return }
-    // TODO: call API to update VM. For now, just persist new attributes and keep ID from state.
-    if plan.ID.IsNull() || plan.ID.ValueString() == "" {
-        plan.ID = state.ID
-    }
-    // Power transitions if changed
-    if r.cl != nil && !plan.Power.IsNull() {
-        if state.Name.ValueString() != "" {
-            _ = r.applyDesiredPower(ctx, &plan)
-        }
-    }
-    resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
-}
-
 func (r *VMResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
     var data vmModel
     resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
@@ -497,34 +438,27 @@ func (r *VMResource) Delete(ctx context.Context, req resource.DeleteRequest, res
         resp.Diagnostics.AddError("provider not configured", "client missing")
         return
     }
+    limit, d := data.Timeouts.Delete(ctx, defaultDeleteTimeout)
+    resp.Diagnostics.Append(d...)
+    if resp.Diagnostics.HasError() { return }
+    ctx, cancel, op := withDeadline(ctx, "delete", limit)
+    defer cancel()
+    defer op.report(ctx, &resp.Diagnostics)
+    op.at("deleting the VM")
+
     // If VM doesn't exist, consider destroy successful
     if data.Name.IsNull() || data.Name.ValueString() == "" { return }
-    _, status, err := r.cl.GetVm(ctx, data.Name.ValueString())
-    if err != nil && status == 404 { return }
+    if _, err := r.cl.GetVm(ctx, data.Name.ValueString()); client.IsNotFound(err) { return }
 
-    // Determine deleteDisks from lifecycle and per-disk protect flags
+    // The server never deletes disks: it cannot tell created files from attached ones. Files this
+    // resource created are deleted one by one below.
     force := true
     delDisks := false
-    if data.Lifecycle != nil && !data.Lifecycle.DeleteDisks.IsNull() {
-        delDisks = data.Lifecycle.DeleteDisks.ValueBool()
-    }
-    // If any disk marked protect=true, ensure deleteDisks=false
-    if delDisks && len(data.Disks) > 0 {
-        for _, d := range data.Disks {
-            if !d.Protect.IsNull() && d.Protect.ValueBool() {
-                delDisks = false
-                break
-            }
-        }
-    }
     out, err := r.cl.DeleteVm(ctx, data.Name.ValueString(), client.DeleteVmRequest{Force: &force, DeleteDisks: &delDisks})
     if err != nil {
-        // Heuristic: treat not found as success
-        e := strings.ToLower(err.Error())
-        if strings.Contains(e, "not found") || strings.Contains(e, "does not exist") || strings.Contains(e, "objectnotfound") {
-            return
-        }
-        resp.Diagnostics.AddError("delete failed", err.Error())
+        // Already gone (e.g. deleted concurrently) counts as a successful destroy
+        if client.IsNotFound(err) { return }
+        resp.Diagnostics.AddError("delete failed", client.Detail(err))
         return
     }
 	// Emit the server's delete response as a Warning so it's visible in CLI output
@@ -533,6 +467,47 @@ func (r *VMResource) Delete(ctx context.Context, req resource.DeleteRequest, res
 			resp.Diagnostics.AddWarning("delete response", string(b))
 		}
 	}
+	op.at("deleting disk files")
+	if deleteDisks(&data) { r.deleteOwnedDisks(ctx, &data, readOwnership(ctx, req.Private, &resp.Diagnostics), &resp.Diagnostics) }
+}
+
+// deleteOwnedDisks removes the disk files this resource created, skipping protected disks. State
+// without an ownership record (written by an older provider) deletes nothing.
+func (r *VMResource) deleteOwnedDisks(ctx context.Context, m *vmModel, owned *diskOwnership, diags *diag.Diagnostics) {
+    if !owned.recorded {
+        diags.AddWarning("disk files kept", "This VM has no record of which disk files Terraform created, so none were deleted. Remove them by hand if they are no longer needed.")
+        return
+    }
+    protected := map[string]bool{}
+    for i := range m.Disks {
+        if m.Disks[i].Protect.ValueBool() { protected[normPath(diskFile(&m.Disks[i]))] = true }
+    }
+    for _, file := range owned.Created {
+        if protected[normPath(file)] { continue }
+        if err := r.cl.DeleteDisk(ctx, file); err != nil && !client.IsNotFound(err) {
+            // The VM is gone; a leftover file should not keep it in state
+            diags.AddWarning("disk delete failed", file+" was not deleted: "+client.Detail(err))
+        }
+    }
+}
+
+// settleUnknowns resolves computed disk and network interface attributes nothing filled during apply.
+func settleUnknowns(m *vmModel) {
+    for i := range m.Disks {
+        d := &m.Disks[i]
+        if d.Size.IsUnknown() { d.Size = types.StringNull() }
+        if d.Path.IsUnknown() { d.Path = types.StringNull() }
+        if d.Protect.IsUnknown() { d.Protect = types.BoolValue(false) }
+        if d.Controller.IsUnknown() { d.Controller = types.StringNull() }
+        if d.ControllerNumber.IsUnknown() { d.ControllerNumber = types.Int64Null() }
+        if d.Lun.IsUnknown() { d.Lun = types.Int64Null() }
+    }
+    if m.DynamicMemory != nil && m.DynamicMemory.Enabled.IsUnknown() { m.DynamicMemory.Enabled = types.BoolValue(true) }
+    for i := range m.NetworkInterfaces {
+        n := &m.NetworkInterfaces[i]
+        if n.MacAddress.IsUnknown() { n.MacAddress = types.StringNull() }
+        if n.IsConnected.IsUnknown() { n.IsConnected = types.BoolValue(true) }
+    }
 }
 
 // toMB parses values like "2048", "2048MB", "2GB" into MB
@@ -547,43 +522,3 @@ func toMB(s string) (int, bool) {
 	if unitGB { return n * 1024, true }
 	return n, true
 }
-
-// applyDesiredPower starts/stops the VM to match desired state, with optional stop method and wait
-func (r *VMResource) applyDesiredPower(ctx context.Context, m *vmModel) error {
-    if r.cl == nil || m == nil || m.Name.IsNull() { return nil }
-    desired := strings.ToLower(m.Power.ValueString())
-    if desired == "" { return nil }
-    stopMethod := strings.ToLower(m.StopMethod.ValueString())
-    timeoutSec := 0
-    if !m.WaitTimeoutSec.IsNull() { timeoutSec = int(m.WaitTimeoutSec.ValueInt64()) }
-    if timeoutSec <= 0 { timeoutSec = 240 }
-
-    switch desired {
-    case "running":
-        if err := r.cl.StartVm(ctx, m.Name.ValueString()); err != nil { return err }
-        _ = r.waitForPower(ctx, m.Name.ValueString(), "running", timeoutSec)
-    case "stopped":
-        var force, turnOff bool
-        if stopMethod == "turnoff" { turnOff = true } else if stopMethod == "force" { force = true }
-        if err := r.cl.StopVm(ctx, m.Name.ValueString(), force, turnOff); err != nil { return err }
-        _ = r.waitForPower(ctx, m.Name.ValueString(), "stopped", timeoutSec)
-    }
-    return nil
-}
-
-func (r *VMResource) waitForPower(ctx context.Context, name string, desired string, timeoutSec int) error {
-    // Best-effort polling using GetVm; expect out["state"] string like "Off"/"Running"
-    deadline := time.Now().Add(time.Duration(timeoutSec) * time.Second)
-    desiredLower := strings.ToLower(desired)
-    for time.Now().Before(deadline) {
-        if out, _, err := r.cl.GetVm(ctx, name); err == nil {
-            if s, ok := out["state"].(string); ok {
-                sl := strings.ToLower(s)
-                if desiredLower == "running" && (sl == "running" || sl == "on") { return nil }
-                if desiredLower == "stopped" && (sl == "off" || sl == "stopped") { return nil }
-            }
-        }
-        time.Sleep(2 * time.Second)
-    }
-    return nil
-}
@@ -0,0 +1,96 @@
+package resources
+
+import (
+    "context"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// createProgress records what Create has made on the host so a failure can be compensated.
+type createProgress struct {
+    vm     bool   // the VM exists
+    vmID   string
+    jobs   []*diskJob
+    osFile *string // OS disk created by CreateVm without a disk block (new_vhd_path)
+}
+
+// createdFiles lists disk files Create made itself: finished clones and new disks. Attached
+// files and new disks whose creation failed (their path may hold someone else's file) are left out.
+func (p *createProgress) createdFiles() []string {
+    var out []string
+    for _, j := range p.jobs {
+        if j != nil && j.created && j.kind != diskAttach && j.path != "" { out = append(out, j.path) }
+    }
+    if p.osFile != nil && p.vm { out = append(out, *p.osFile) }
+    return out
+}
+
+func validateCreateFailure(m *vmModel, diags *diag.Diagnostics) {
+    v := m.OnCreateFailure.ValueString()
+    if v == "" || m.OnCreateFailure.IsUnknown() || strings.EqualFold(v, "rollback") || strings.EqualFold(v, "keep") { return }
+    diags.AddAttributeError(path.Root("on_create_failure"), "invalid on_create_failure", v+" is not rollback or keep.")
+}
+
+// createFailed compensates a failed Create per on_create_failure: rollback (the default) deletes
+// the VM and the disk files Create made; keep saves what exists as state, which Terraform then
+// marks tainted so the next apply replaces it.
+func (r *VMResource) createFailed(ctx context.Context, m *vmModel, p *createProgress, resp *resource.CreateResponse) {
+    name := m.Name.ValueString()
+    if p.vm {
+        // Learn where the server placed disks while the VM still references them
+        for _, j := range p.jobs {
+            if j != nil && j.created && j.path == "" { r.recordDiskPaths(ctx, m, p.jobs); break }
+        }
+    }
+    files := p.createdFiles()
+    if !p.vm && len(files) == 0 { return }
+
+    if strings.EqualFold(m.OnCreateFailure.ValueString(), "keep") {
+        if !p.vm {
+            resp.Diagnostics.AddWarning("create failed, files kept", "on_create_failure = \"keep\": these disk files were created before the VM and were not deleted: "+strings.Join(files, ", "))
+            return
+        }
+        m.ID = types.StringValue(name)
+        if p.vmID != "" { m.ID = types.StringValue(p.vmID) }
+        if m.CPU.IsUnknown() { m.CPU = types.Int64Null() }
+        if m.Memory.IsUnknown() { m.Memory = types.StringNull() }
+        settleUnknowns(m)
+        owned := &diskOwnership{Created: files, recorded: true}
+        for _, j := range p.jobs {
+            if j != nil && j.kind == diskAttach && j.created { owned.Attached = appendPath(owned.Attached, j.path) }
+        }
+        writeOwnership(ctx, resp.Private, owned, &resp.Diagnostics)
+        resp.Diagnostics.Append(resp.State.Set(ctx, m)...)
+        resp.Diagnostics.AddWarning("partial VM kept", "on_create_failure = \"keep\": VM "+name+" stays on the host and in state as tainted; the next apply replaces it.")
+        return
+    }
+
+    var cleaned, failed []string
+    if p.vm {
+        force, keepDisks := true, false
+        if _, err := r.cl.DeleteVm(ctx, name, client.DeleteVmRequest{Force: &force, DeleteDisks: &keepDisks}); err != nil && !client.IsNotFound(err) {
+            // Files of a VM that still exists stay in place
+            resp.Diagnostics.AddError("rollback incomplete", "Could not delete VM "+name+"; it and its disks are left on the host: "+client.Detail(err))
+            return
+        }
+        cleaned = append(cleaned, "VM "+name)
+    }
+    for _, f := range files {
+        if err := r.cl.DeleteDisk(ctx, f); err != nil && !client.IsNotFound(err) {
+            failed = append(failed, f+" ("+client.Detail(err)+")")
+            continue
+        }
+        cleaned = append(cleaned, f)
+    }
+    if len(failed) > 0 {
+        resp.Diagnostics.AddError("rollback incomplete", "Removed: "+strings.Join(cleaned, ", ")+"\nCould not delete: "+strings.Join(failed, "; "))
+        return
+    }
+    resp.Diagnostics.AddWarning("create rolled back", "on_create_failure = \"rollback\" removed: "+strings.Join(cleaned, ", "))
+}
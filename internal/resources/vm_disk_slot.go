@@ -0,0 +1,93 @@
+package resources
+
+import (
+    "fmt"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// controllerType returns the disk's controller as the API spells it, defaulting to the
+// generation's boot controller: IDE on Generation 1, SCSI on Generation 2.
+func controllerType(d *diskModel, gen int64) string {
+    if strings.EqualFold(d.Controller.ValueString(), "ide") { return "IDE" }
+    if d.Controller.ValueString() == "" && gen == 1 { return "IDE" }
+    return "SCSI"
+}
+
+func known64(v types.Int64) *int {
+    if v.IsNull() || v.IsUnknown() { return nil }
+    n := int(v.ValueInt64())
+    return &n
+}
+
+// diskSlot returns the controller placement requested for d; nil leaves it to the server.
+func diskSlot(d *diskModel, gen int64) *client.DiskSlot {
+    num, lun := known64(d.ControllerNumber), known64(d.Lun)
+    if (d.Controller.IsNull() || d.Controller.IsUnknown()) && num == nil && lun == nil { return nil }
+    return &client.DiskSlot{ControllerType: controllerType(d, gen), ControllerNumber: num, ControllerLocation: lun}
+}
+
+// slotMoved reports whether the plan puts a disk somewhere other than where it is attached.
+func slotMoved(prior, d *diskModel, gen int64) bool {
+    if diskSlot(d, gen) == nil { return false }
+    if !d.Controller.IsUnknown() && !d.Controller.IsNull() && controllerType(d, gen) != controllerType(prior, gen) { return true }
+    if n := known64(d.ControllerNumber); n != nil && !d.ControllerNumber.Equal(prior.ControllerNumber) { return true }
+    if l := known64(d.Lun); l != nil && !d.Lun.Equal(prior.Lun) { return true }
+    return false
+}
+
+// validateDiskSlots checks controller, controller_number and lun against the generation and for
+// two disks claiming the same location.
+func validateDiskSlots(m *vmModel, diags *diag.Diagnostics) {
+    gen := generation(m)
+    taken := map[string]int{}
+    for i := range m.Disks {
+        d := &m.Disks[i]
+        at := path.Root("disk").AtListIndex(i)
+        if c := d.Controller.ValueString(); c != "" && !d.Controller.IsUnknown() {
+            switch {
+            case !strings.EqualFold(c, "scsi") && !strings.EqualFold(c, "ide"):
+                diags.AddAttributeError(at.AtName("controller"), "invalid controller", c+" is not scsi or ide.")
+                continue
+            case strings.EqualFold(c, "ide") && gen != 1:
+                diags.AddAttributeError(at.AtName("controller"), "invalid controller", "Generation 2 VMs have no IDE controller; use scsi.")
+                continue
+            }
+        }
+        ctrl := controllerType(d, gen)
+        maxNum, maxLun := 3, 63
+        if ctrl == "IDE" { maxNum, maxLun = 1, 1 }
+        num, lun := known64(d.ControllerNumber), known64(d.Lun)
+        if num != nil && (*num < 0 || *num > maxNum) {
+            diags.AddAttributeError(at.AtName("controller_number"), "invalid controller number", fmt.Sprintf("%s controller_number must be 0-%d.", ctrl, maxNum))
+            continue
+        }
+        if lun != nil && (*lun < 0 || *lun > maxLun) {
+            diags.AddAttributeError(at.AtName("lun"), "invalid lun", fmt.Sprintf("%s lun must be 0-%d.", ctrl, maxLun))
+            continue
+        }
+        if lun == nil { continue }
+        n := 0
+        if num != nil { n = *num }
+        key := fmt.Sprintf("%s %d:%d", ctrl, n, *lun)
+        if j, ok := taken[key]; ok {
+            diags.AddAttributeError(at.AtName("lun"), "disk location in use", fmt.Sprintf("disk[%d] is already placed at %s.", j, key))
+            continue
+        }
+        taken[key] = i
+    }
+}
+
+// applySlot records where the host attached a disk, keeping the configured controller spelling.
+func applySlot(d *diskModel, a *client.AttachedDisk) {
+    if a.ControllerType != "" && !strings.EqualFold(d.Controller.ValueString(), a.ControllerType) {
+        d.Controller = types.StringValue(strings.ToLower(a.ControllerType))
+    }
+    d.ControllerNumber = types.Int64Value(int64(a.ControllerNumber))
+    d.Lun = types.Int64Value(int64(a.ControllerLocation))
+}
@@ -0,0 +1,251 @@
+package resources
+
+import (
+    "context"
+    "fmt"
+    "path/filepath"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// diskDiff is the per-disk difference between state and plan, with disks matched as priorIndex
+// does.
+type diskDiff struct {
+    added   []int       // plan indexes
+    removed []diskModel // state entries
+    changed []diskDelta
+}
+
+// diskDelta is an in-place change to a disk present in both state and plan.
+type diskDelta struct {
+    idx    int // plan index
+    prior  *diskModel
+    growGB *int
+    shrink bool
+    retype string
+    move   bool // controller, controller_number or lun changed
+}
+
+// sameSource reports whether planned disk d still refers to the file behind prior. A new clone
+// source, attach source or explicit path means a different disk, which is replaced. State without
+// a source_path (imported disks, created ones) is compared by the file it resolves to instead.
+func sameSource(prior, d *diskModel) bool {
+    if prior.CloneFrom.ValueString() != d.CloneFrom.ValueString() { return false }
+    ps, ds := prior.SourcePath.ValueString(), d.SourcePath.ValueString()
+    switch {
+    case ps != "" && ds != "":
+        if normPath(ps) != normPath(ds) { return false }
+    case ds != "":
+        if normPath(ds) != normPath(diskFile(prior)) { return false }
+    case ps != "":
+        if normPath(diskFile(d)) != normPath(ps) { return false }
+    }
+    if !d.Path.IsUnknown() && d.Path.ValueString() != "" && prior.Path.ValueString() != "" {
+        return normPath(prior.Path.ValueString()) == normPath(d.Path.ValueString())
+    }
+    return true
+}
+
+func diffDisks(state, plan []diskModel, gen int64) diskDiff {
+    var out diskDiff
+    matched := map[int]bool{}
+    for i := range plan {
+        d := &plan[i]
+        j := priorIndex(state, i, d)
+        if j < 0 || matched[j] || !sameSource(&state[j], d) {
+            out.added = append(out.added, i)
+            continue
+        }
+        matched[j] = true
+        delta := diskDelta{idx: i, prior: &state[j]}
+        pm, pok := toMB(d.Size.ValueString())
+        sm, sok := toMB(state[j].Size.ValueString())
+        if pok && sok && !d.Size.IsUnknown() && pm != sm {
+            if pm < sm {
+                delta.shrink = true
+            } else {
+                delta.growGB = diskSizeGB(d)
+            }
+        }
+        if t := d.Type.ValueString(); t != "" && !d.Type.IsUnknown() && !strings.EqualFold(t, state[j].Type.ValueString()) {
+            delta.retype = t
+        }
+        delta.move = slotMoved(&state[j], d, gen)
+        if delta.shrink || delta.growGB != nil || delta.retype != "" || delta.move { out.changed = append(out.changed, delta) }
+    }
+    for j := range state {
+        if !matched[j] { out.removed = append(out.removed, state[j]) }
+    }
+    return out
+}
+
+// needsOffline reports whether a disk operation requires the VM powered off: anything on an IDE
+// controller (the Generation 1 default), resizing a legacy .vhd, and every type conversion.
+func needsOffline(op string, d *diskModel, gen int64) bool {
+    ctrl := d.Controller.ValueString()
+    ide := strings.EqualFold(ctrl, "ide") || (ctrl == "" && gen == 1)
+    switch op {
+    case "retype":
+        return true
+    case "resize":
+        return ide || strings.EqualFold(filepath.Ext(diskFile(d)), ".vhd")
+    }
+    return ide
+}
+
+func generation(m *vmModel) int64 {
+    if g := m.Generation.ValueInt64(); g > 0 { return g }
+    return 2
+}
+
+func diskLabel(d *diskModel) string {
+    if n := d.Name.ValueString(); n != "" { return "disk " + n }
+    if p := diskFile(d); p != "" { return "disk " + p }
+    return "new disk"
+}
+
+// notOwned explains why a disk this resource only attached or imported is not resized or converted.
+func notOwned(d *diskModel) string {
+    return diskLabel(d) + " was attached (source_path) or imported rather than created by this resource, so " +
+        diskFile(d) + " may be shared and is not resized or converted in place. Change the file outside Terraform and update size/type to match."
+}
+
+func deleteDisks(m *vmModel) bool {
+    return m.Lifecycle != nil && m.Lifecycle.DeleteDisks.ValueBool()
+}
+
+// planDiskChanges refuses shrinking, and growing or converting files this resource did not create,
+// at plan time and lists the changes that need the VM powered off.
+func planDiskChanges(plan, state *vmModel, owned *diskOwnership, diags *diag.Diagnostics) {
+    gen := generation(plan)
+    diff := diffDisks(state.Disks, plan.Disks, gen)
+    var offline []string
+    if !plan.CPU.IsUnknown() && !plan.CPU.IsNull() && !plan.CPU.Equal(state.CPU) {
+        offline = append(offline, "cpu change")
+    }
+    if dynamicToggled(plan, state) { offline = append(offline, "dynamic memory on/off") }
+    if tpm, encrypt := securityChanges(plan, state); tpm != nil || encrypt != nil { offline = append(offline, "security change") }
+    if secureBootChanged(plan, state) { offline = append(offline, "secure boot change") }
+    for _, d := range diff.removed {
+        if diskFile(&d) != "" && needsOffline("detach", &d, gen) { offline = append(offline, "detach "+diskLabel(&d)) }
+    }
+    for _, c := range diff.changed {
+        d := &plan.Disks[c.idx]
+        if c.shrink {
+            diags.AddAttributeError(path.Root("disk").AtListIndex(c.idx).AtName("size"), "disk shrink not supported",
+                fmt.Sprintf("%s cannot shrink from %s to %s. Virtual disks only grow in place; keep the size or replace the disk.", diskLabel(d), c.prior.Size.ValueString(), d.Size.ValueString()))
+        }
+        if (c.retype != "" || c.growGB != nil) && !owned.owns(diskFile(c.prior)) {
+            at := path.Root("disk").AtListIndex(c.idx).AtName("size")
+            if c.retype != "" { at = at.ParentPath().AtName("type") }
+            diags.AddAttributeError(at, "disk not owned", notOwned(c.prior))
+            continue
+        }
+        if c.retype != "" { offline = append(offline, "convert "+diskLabel(d)+" to "+c.retype) }
+        if c.growGB != nil && needsOffline("resize", c.prior, gen) { offline = append(offline, "grow "+diskLabel(d)) }
+        if c.move && (needsOffline("detach", c.prior, gen) || needsOffline("attach", d, gen)) { offline = append(offline, "move "+diskLabel(d)) }
+    }
+    for _, i := range diff.added {
+        if needsOffline("attach", &plan.Disks[i], gen) { offline = append(offline, "attach "+diskLabel(&plan.Disks[i])) }
+    }
+    offline = append(offline, nicOffline(plan, state)...)
+    if len(offline) > 0 {
+        diags.AddWarning("update needs the VM powered off",
+            "These changes cannot be made while the VM runs: "+strings.Join(offline, "; ")+". A running VM is stopped with stop_method and started again afterwards.")
+    }
+}
+
+// reconcileDisks applies the disk list difference in place: detach removed disks (deleting files
+// this resource created when vm_lifecycle.delete_disks allows), grow, convert or move changed
+// ones, then create, clone or attach added ones. owned is updated to match.
+func (r *VMResource) reconcileDisks(ctx context.Context, off *offlineSession, plan, state *vmModel, owned *diskOwnership, diags *diag.Diagnostics) {
+    gen := generation(plan)
+    diff := diffDisks(state.Disks, plan.Disks, gen)
+    name := state.Name.ValueString()
+    stopFor := func(what string) bool {
+        if err := off.stop(ctx); err != nil {
+            diags.AddError("stop for "+what+" failed", client.Detail(err))
+            return false
+        }
+        return true
+    }
+
+    for _, d := range diff.removed {
+        file := diskFile(&d)
+        if file == "" { continue }
+        if needsOffline("detach", &d, gen) && !stopFor("disk detach") { return }
+        if err := r.cl.DetachDisk(ctx, name, file); err != nil && !client.IsNotFound(err) {
+            diags.AddError("disk detach failed", diskLabel(&d)+": "+client.Detail(err))
+            return
+        }
+        if !owned.owns(file) || d.Protect.ValueBool() || !deleteDisks(plan) {
+            tflog.Info(ctx, "disk detached, file kept", map[string]any{"vm": name, "path": file})
+            owned.forget(file)
+            continue
+        }
+        if err := r.cl.DeleteDisk(ctx, file); err != nil && !client.IsNotFound(err) {
+            diags.AddError("disk delete failed", "Detached "+file+" but could not delete it: "+client.Detail(err))
+            return
+        }
+        owned.forget(file)
+    }
+
+    for _, c := range diff.changed {
+        at := path.Root("disk").AtListIndex(c.idx)
+        file := diskFile(c.prior)
+        if c.shrink {
+            diags.AddAttributeError(at.AtName("size"), "disk shrink not supported", diskLabel(c.prior)+" only grows in place.")
+            return
+        }
+        if (c.retype != "" || c.growGB != nil) && !owned.owns(file) {
+            diags.AddAttributeError(at, "disk not owned", notOwned(c.prior))
+            return
+        }
+        if c.retype != "" {
+            if !stopFor("disk conversion") { return }
+            if err := r.cl.ConvertDisk(ctx, file, c.retype); err != nil {
+                diags.AddAttributeError(at.AtName("type"), "disk conversion failed", client.Detail(err))
+                return
+            }
+        }
+        if c.growGB != nil {
+            if needsOffline("resize", c.prior, gen) && !stopFor("disk resize") { return }
+            if err := r.cl.ResizeDisk(ctx, file, *c.growGB); err != nil {
+                diags.AddAttributeError(at.AtName("size"), "disk resize failed", client.Detail(err))
+                return
+            }
+        }
+        if c.move {
+            d := &plan.Disks[c.idx]
+            if (needsOffline("detach", c.prior, gen) || needsOffline("attach", d, gen)) && !stopFor("disk move") { return }
+            if err := r.cl.DetachDisk(ctx, name, file); err != nil && !client.IsNotFound(err) {
+                diags.AddAttributeError(at, "disk move failed", "Could not detach "+file+": "+client.Detail(err))
+                return
+            }
+            if err := r.cl.AttachDisk(ctx, name, file, d.ReadOnly.ValueBool(), nil, nil, nil, diskSlot(d, gen)); err != nil {
+                diags.AddAttributeError(at.AtName("lun"), "disk move failed", "Detached "+file+" but could not attach it at the new location: "+client.Detail(err))
+                return
+            }
+        }
+    }
+
+    if len(diff.added) == 0 { return }
+    add := map[int]bool{}
+    for _, i := range diff.added {
+        add[i] = true
+        if needsOffline("attach", &plan.Disks[i], gen) && !stopFor("disk attach") { return }
+    }
+    jobs := r.resolveDisks(ctx, plan, func(i int) bool { return add[i] }, diags)
+    if diags.HasError() { return }
+    r.runClones(ctx, plan, jobs, diags)
+    if diags.HasError() { return }
+    r.attachDisks(ctx, name, jobs, -1, diags)
+    if diags.HasError() { return }
+    r.recordDiskPaths(ctx, plan, jobs)
+    owned.record(jobs)
+}
@@ -0,0 +1,261 @@
+package resources
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "sync"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+const (
+    diskNew    = "new"
+    diskClone  = "clone"
+    diskAttach = "attach"
+)
+
+// diskJob is one disk block resolved for create: what to do and where the file lives.
+type diskJob struct {
+    idx      int
+    kind     string // new | clone | attach
+    path     string // resolved target; empty when placement is left to the server
+    sizeGB   *int
+    vhdType  *string
+    parent   *string
+    readOnly bool
+    slot     *client.DiskSlot // nil leaves controller placement to the server
+    created  bool             // the file was cloned, created or attached by this apply
+}
+
+func diskKind(d *diskModel) string {
+    if d.SourcePath.ValueString() != "" { return diskAttach }
+    if d.CloneFrom.ValueString() != "" { return diskClone }
+    return diskNew
+}
+
+// diskSizeGB converts the disk size to whole GB (minimum 1); nil when unset.
+func diskSizeGB(d *diskModel) *int {
+    mb, ok := toMB(d.Size.ValueString())
+    if !ok { return nil }
+    g := mb / 1024
+    if g <= 0 { g = 1 }
+    return &g
+}
+
+// diskOrder returns disk indexes so that every disk comes after the sibling named in its
+// placement.co_locate_with. References to anything other than a sibling name are left to the server.
+func diskOrder(disks []diskModel) ([]int, error) {
+    byName := map[string]int{}
+    for i := range disks {
+        if n := disks[i].Name.ValueString(); n != "" { byName[n] = i }
+    }
+    dep := make([]int, len(disks))
+    for i := range disks {
+        dep[i] = -1
+        if p := disks[i].Placement; p != nil {
+            if j, ok := byName[p.CoLocateWith.ValueString()]; ok && j != i { dep[i] = j }
+        }
+    }
+    order := make([]int, 0, len(disks))
+    state := make([]int, len(disks)) // 0 = unvisited, 1 = visiting, 2 = done
+    var visit func(i int) error
+    visit = func(i int) error {
+        switch state[i] {
+        case 1:
+            return fmt.Errorf("disk %q: placement.co_locate_with forms a cycle", disks[i].Name.ValueString())
+        case 2:
+            return nil
+        }
+        state[i] = 1
+        if dep[i] >= 0 {
+            if err := visit(dep[i]); err != nil { return err }
+        }
+        state[i] = 2
+        order = append(order, i)
+        return nil
+    }
+    for i := range disks {
+        if err := visit(i); err != nil { return nil, err }
+    }
+    return order, nil
+}
+
+// placementRequest builds the plan-disk request for disk d. coLocate overrides co_locate_with with
+// a sibling's resolved path.
+func placementRequest(vmName string, d *diskModel, purpose, coLocate string) client.DiskPlanRequest {
+    req := client.DiskPlanRequest{VMName: vmName, Operation: "create", Purpose: purpose}
+    if src := d.CloneFrom.ValueString(); src != "" { req.Operation = "clone"; req.CloneFrom = &src }
+    if sz := diskSizeGB(d); sz != nil && req.Operation == "create" { req.SizeGB = sz }
+    if d.Placement != nil {
+        if s := d.Placement.PreferRoot.ValueString(); s != "" { req.PreferRoot = &s }
+        if s := d.Placement.CoLocateWith.ValueString(); s != "" { req.CoLocateWith = &s }
+        if n := d.Placement.MinFreeGB.ValueInt64(); n > 0 { mf := int(n); req.MinFreeGB = &mf }
+    }
+    if coLocate != "" { req.CoLocateWith = &coLocate }
+    return req
+}
+
+// resolveDisks decides the file for every disk block accepted by include (nil means all), asking
+// plan-disk for auto-placed ones in co_locate_with order so siblings can be placed next to each
+// other. Jobs for excluded disks are nil.
+func (r *VMResource) resolveDisks(ctx context.Context, m *vmModel, include func(int) bool, diags *diag.Diagnostics) []*diskJob {
+    order, err := diskOrder(m.Disks)
+    if err != nil {
+        diags.AddAttributeError(path.Root("disk"), "invalid disk placement", err.Error())
+        return nil
+    }
+    osIdx := osDiskIndex(m.Disks)
+    gen := generation(m)
+    jobs := make([]*diskJob, len(m.Disks))
+    resolved := map[string]string{} // disk name -> path
+    for _, i := range order {
+        d := &m.Disks[i]
+        if include != nil && !include(i) {
+            // Existing disks can still anchor co_locate_with for new siblings
+            if n, p := d.Name.ValueString(), d.Path.ValueString(); n != "" && p != "" { resolved[n] = p }
+            continue
+        }
+        at := path.Root("disk").AtListIndex(i)
+        j := &diskJob{idx: i, kind: diskKind(d), sizeGB: diskSizeGB(d), readOnly: d.ReadOnly.ValueBool(), slot: diskSlot(d, gen)}
+        if t := d.Type.ValueString(); t != "" { j.vhdType = &t }
+        if p := d.ParentPath.ValueString(); p != "" { j.parent = &p }
+        jobs[i] = j
+        switch {
+        case j.kind == diskAttach:
+            j.path = d.SourcePath.ValueString()
+        case d.Path.ValueString() != "":
+            j.path = d.Path.ValueString()
+        default:
+            coLocate := ""
+            if d.Placement != nil { coLocate = resolved[d.Placement.CoLocateWith.ValueString()] }
+            req := placementRequest(m.Name.ValueString(), d, diskPurpose(d, i == osIdx), coLocate)
+            out, perr := r.cl.PlanDisk(ctx, req)
+            switch {
+            case perr == nil && out != nil && out.Path != "":
+                j.path = out.Path
+            case j.kind == diskClone:
+                diags.AddAttributeError(at, "clone plan failed", "Could not place the clone target: "+planErr(perr))
+                return nil
+            case i != osIdx:
+                diags.AddAttributeError(at, "disk auto-placement failed", "Set disk.path or fix plan-disk: "+planErr(perr))
+                return nil
+            case r.cl.Strict():
+                diags.AddAttributeError(at, "disk auto-placement failed", planErr(perr))
+                return nil
+            default:
+                // The OS disk can still be placed by the server during create
+                diags.AddAttributeWarning(at, "disk auto-placement failed", planErr(perr))
+            }
+        }
+        if n := d.Name.ValueString(); n != "" && j.path != "" { resolved[n] = j.path }
+    }
+    return jobs
+}
+
+func planErr(err error) string {
+    if err == nil { return "plan-disk returned no path" }
+    return client.Detail(err)
+}
+
+// runClones copies every clone disk concurrently within the operation deadline. The first failure
+// cancels the other copies, which in turn cancels their server tasks.
+func (r *VMResource) runClones(ctx context.Context, m *vmModel, jobs []*diskJob, diags *diag.Diagnostics) {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    var wg sync.WaitGroup
+    var mu sync.Mutex
+    failed := map[int]error{}
+    for _, j := range jobs {
+        if j == nil || j.kind != diskClone { continue }
+        src := m.Disks[j.idx].CloneFrom.ValueString()
+        tflog.Debug(ctx, "clone enqueue", map[string]any{"from": src, "to": j.path})
+        wg.Add(1)
+        go func(j *diskJob) {
+            defer wg.Done()
+            if err := r.cloneDisk(ctx, src, j.path); err != nil {
+                mu.Lock()
+                failed[j.idx] = err
+                mu.Unlock()
+                cancel()
+                return
+            }
+            j.created = true
+        }(j)
+    }
+    wg.Wait()
+    independent := false
+    for _, err := range failed {
+        if !errors.Is(err, context.Canceled) { independent = true }
+    }
+    for _, j := range jobs {
+        if j == nil || j.kind != diskClone { continue }
+        err, bad := failed[j.idx]
+        if !bad {
+            tflog.Debug(ctx, "clone complete", map[string]any{"target": j.path})
+            continue
+        }
+        // Siblings canceled because of another clone's failure are not worth a separate error
+        if independent && errors.Is(err, context.Canceled) { continue }
+        diags.AddAttributeError(path.Root("disk").AtListIndex(j.idx), "clone failed", client.Detail(err))
+    }
+}
+
+func (r *VMResource) cloneDisk(ctx context.Context, src, target string) error {
+    prep, err := r.cl.ClonePrepare(ctx, client.ClonePrepareRequest{SourcePath: src, TargetPath: &target})
+    if err != nil { return fmt.Errorf("prepare: %w", err) }
+    id, err := r.cl.CloneEnqueue(ctx, prep.Token)
+    if err != nil { return fmt.Errorf("enqueue: %w", err) }
+    _, err = r.cl.WaitCloneTask(ctx, id)
+    return err
+}
+
+// attachDisks connects every disk except skip (the disk already created with the VM). New disks are
+// created at their path by the attach call.
+func (r *VMResource) attachDisks(ctx context.Context, name string, jobs []*diskJob, skip int, diags *diag.Diagnostics) {
+    for _, j := range jobs {
+        if j == nil || j.idx == skip { continue }
+        var size, vtype, parent = j.sizeGB, j.vhdType, j.parent
+        if j.kind != diskNew { size, vtype, parent = nil, nil, nil }
+        if err := r.cl.AttachDisk(ctx, name, j.path, j.readOnly, size, vtype, parent, j.slot); err != nil {
+            diags.AddAttributeError(path.Root("disk").AtListIndex(j.idx), "attach failed", client.Detail(err))
+            return
+        }
+        j.created = true
+    }
+}
+
+// recordDiskPaths writes resolved paths and controller locations into state. Disks the server
+// placed itself are matched against the attached list.
+func (r *VMResource) recordDiskPaths(ctx context.Context, m *vmModel, jobs []*diskJob) {
+    attached, _ := r.cl.ListAttachedDisks(ctx, m.Name.ValueString())
+    known := map[string]bool{}
+    for i := range m.Disks {
+        if p := m.Disks[i].Path.ValueString(); p != "" { known[normPath(p)] = true }
+    }
+    for _, j := range jobs {
+        if j != nil && j.path != "" { known[normPath(j.path)] = true }
+    }
+    for _, j := range jobs {
+        if j == nil { continue }
+        d := &m.Disks[j.idx]
+        if j.path == "" {
+            for _, a := range attached {
+                if !known[normPath(a.Path)] { j.path = a.Path; known[normPath(a.Path)] = true; break }
+            }
+        }
+        if j.path != "" {
+            d.Path = types.StringValue(j.path)
+        } else {
+            d.Path = types.StringNull()
+        }
+        for i := range attached {
+            if j.path != "" && normPath(attached[i].Path) == normPath(j.path) { applySlot(d, &attached[i]); break }
+        }
+    }
+}
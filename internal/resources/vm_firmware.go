@@ -0,0 +1,194 @@
+package resources
+
+import (
+    "context"
+    "fmt"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// parseBootDevice reads a boot_order entry: disk, cd or network, optionally followed by
+// ":<reference>" naming a disk block (or VHD path) or a network adapter.
+func parseBootDevice(s string) (client.BootDevice, error) {
+    // Split at the first colon only, so disk:D:/VMs/os.vhdx keeps its drive letter
+    kind, ref, _ := strings.Cut(strings.TrimSpace(s), ":")
+    switch strings.ToLower(kind) {
+    case "disk", "hdd":
+        return client.BootDevice{Type: "Disk", Ref: ref}, nil
+    case "cd", "dvd":
+        return client.BootDevice{Type: "CD", Ref: ref}, nil
+    case "network", "nic", "pxe":
+        return client.BootDevice{Type: "Network", Ref: ref}, nil
+    }
+    return client.BootDevice{}, fmt.Errorf("%q is not a boot device; use disk, cd or network, optionally with :<name>", s)
+}
+
+// bootDevices resolves boot_order (or boot_device) against the VM's disks: a disk reference that
+// names a disk block becomes that disk's file.
+func bootDevices(m *vmModel) ([]client.BootDevice, error) {
+    f := m.Firmware
+    entries := f.BootOrder
+    if len(entries) == 0 && f.BootDevice.ValueString() != "" { entries = []types.String{f.BootDevice} }
+    var out []client.BootDevice
+    for _, e := range entries {
+        d, err := parseBootDevice(e.ValueString())
+        if err != nil { return nil, err }
+        if d.Type == "Disk" && d.Ref != "" {
+            for i := range m.Disks {
+                if m.Disks[i].Name.ValueString() == d.Ref && diskFile(&m.Disks[i]) != "" { d.Ref = diskFile(&m.Disks[i]); break }
+            }
+        }
+        out = append(out, d)
+    }
+    return out, nil
+}
+
+func sameBootDevice(want, got client.BootDevice) bool {
+    if !strings.EqualFold(want.Type, got.Type) { return false }
+    switch {
+    case want.Ref == "":
+        return true
+    case strings.EqualFold(want.Type, "Disk"):
+        return normPath(want.Ref) == normPath(got.Ref)
+    }
+    return strings.EqualFold(want.Ref, got.Ref)
+}
+
+func bootString(d client.BootDevice) string {
+    s := strings.ToLower(d.Type)
+    if d.Ref != "" { s += ":" + d.Ref }
+    return s
+}
+
+// validateFirmware checks the boot settings at plan time. They are Generation 2 (UEFI) only.
+func validateFirmware(m *vmModel, diags *diag.Diagnostics) {
+    f := m.Firmware
+    if f == nil { return }
+    at := path.Root("firmware")
+    if managesBoot(f) && generation(m) == 1 {
+        diags.AddAttributeError(at, "firmware not supported", "boot_order, boot_device, preferred_network_boot_protocol and pause_after_boot_failure need a Generation 2 VM.")
+        return
+    }
+    if len(f.BootOrder) > 0 && !f.BootDevice.IsNull() {
+        diags.AddAttributeError(at.AtName("boot_device"), "conflicting boot settings", "Set either boot_order or boot_device (the first entry alone), not both.")
+    }
+    for i, e := range f.BootOrder {
+        if e.IsUnknown() { continue }
+        if _, err := parseBootDevice(e.ValueString()); err != nil {
+            diags.AddAttributeError(at.AtName("boot_order").AtListIndex(i), "invalid boot device", err.Error())
+        }
+    }
+    if v := f.BootDevice; !v.IsNull() && !v.IsUnknown() {
+        if _, err := parseBootDevice(v.ValueString()); err != nil { diags.AddAttributeError(at.AtName("boot_device"), "invalid boot device", err.Error()) }
+    }
+    if p := f.NetworkBootProtocol.ValueString(); p != "" && !strings.EqualFold(p, "IPv4") && !strings.EqualFold(p, "IPv6") {
+        diags.AddAttributeError(at.AtName("preferred_network_boot_protocol"), "invalid network boot protocol", p+" is not IPv4 or IPv6.")
+    }
+}
+
+// secureBootChanged reports whether the plan changes secure boot, which needs the VM off.
+func secureBootChanged(m, prior *vmModel) bool {
+    f := m.Firmware
+    if f == nil || f.SecureBoot.IsNull() { return false }
+    if prior == nil || prior.Firmware == nil { return true }
+    return !f.SecureBoot.Equal(prior.Firmware.SecureBoot) || !f.SecureBootTemplate.Equal(prior.Firmware.SecureBootTemplate)
+}
+
+// managesBoot reports whether any boot setting is configured.
+func managesBoot(f *firmwareModel) bool {
+    return f != nil && (len(f.BootOrder) > 0 || !f.BootDevice.IsNull() || !f.NetworkBootProtocol.IsNull() || !f.PauseAfterBootFailure.IsNull())
+}
+
+// bootChanged reports whether the plan changes the boot order or network boot settings.
+func bootChanged(m, prior *vmModel) bool {
+    f := m.Firmware
+    if !managesBoot(f) { return false }
+    if prior == nil || prior.Firmware == nil { return true }
+    p := prior.Firmware
+    if len(f.BootOrder) != len(p.BootOrder) { return true }
+    for i := range f.BootOrder {
+        if !f.BootOrder[i].Equal(p.BootOrder[i]) { return true }
+    }
+    return !f.BootDevice.Equal(p.BootDevice) || !f.NetworkBootProtocol.Equal(p.NetworkBootProtocol) || !f.PauseAfterBootFailure.Equal(p.PauseAfterBootFailure)
+}
+
+// applyFirmware sets secure boot and the boot settings that differ from prior (nil on create).
+// Secure boot needs the VM off; boot order changes are tried online first. off is nil on create,
+// where the VM has not been started yet.
+func (r *VMResource) applyFirmware(ctx context.Context, off *offlineSession, m, prior *vmModel, diags *diag.Diagnostics) {
+    f := m.Firmware
+    if f == nil { return }
+    name := m.Name.ValueString()
+    at := path.Root("firmware")
+    stop := func(what string) bool {
+        if off == nil { return true }
+        if err := off.stop(ctx); err != nil {
+            diags.AddError("stop for "+what+" failed", client.Detail(err))
+            return false
+        }
+        return true
+    }
+    boot := bootChanged(m, prior)
+    if secureBootChanged(m, prior) {
+        if !stop("secure boot change") { return }
+        enabled := f.SecureBoot.ValueBool()
+        tflog.Debug(ctx, "setting secure boot", map[string]any{"vm": name, "secure_boot": enabled, "template": f.SecureBootTemplate.ValueString()})
+        if err := r.cl.SetSecureBoot(ctx, name, enabled, f.SecureBootTemplate.ValueString()); err != nil {
+            diags.AddAttributeError(at.AtName("secure_boot"), "firmware secure-boot", client.Detail(err))
+            return
+        }
+        // Without an explicit boot order, boot the primary disk first as before
+        if prior == nil && len(f.BootOrder) == 0 && f.BootDevice.IsNull() {
+            if err := r.cl.SetFirstBootToPrimaryDisk(ctx, name); err != nil {
+                diags.AddAttributeError(at, "firmware first-boot", client.Detail(err))
+                return
+            }
+        }
+    }
+    if !boot { return }
+    devices, err := bootDevices(m)
+    if err != nil {
+        diags.AddAttributeError(at.AtName("boot_order"), "invalid boot device", err.Error())
+        return
+    }
+    req := client.SetFirmwareRequest{BootOrder: devices}
+    if p := f.NetworkBootProtocol.ValueString(); p != "" { req.PreferredNetworkBootProtocol = &p }
+    if !f.PauseAfterBootFailure.IsNull() { b := f.PauseAfterBootFailure.ValueBool(); req.PauseAfterBootFailure = &b }
+    err = r.cl.SetFirmware(ctx, name, req)
+    if err != nil && off != nil && client.IsConflict(err) {
+        if !stop("firmware change") { return }
+        err = r.cl.SetFirmware(ctx, name, req)
+    }
+    if err != nil {
+        diags.AddAttributeError(at, "firmware update failed", client.Detail(err))
+    }
+}
+
+// refreshFirmware maps the host boot settings onto the managed firmware attributes. boot_order
+// only has to lead the host order; the host lists every device, the configuration usually fewer.
+func refreshFirmware(m *vmModel, fw *client.VmFirmware) {
+    f := m.Firmware
+    if len(f.BootOrder) > 0 {
+        want, err := bootDevices(m)
+        match := err == nil && len(want) <= len(fw.BootOrder)
+        for i := 0; match && i < len(want); i++ { match = sameBootDevice(want[i], fw.BootOrder[i]) }
+        if !match && len(fw.BootOrder) > 0 {
+            f.BootOrder = make([]types.String, len(fw.BootOrder))
+            for i, d := range fw.BootOrder { f.BootOrder[i] = types.StringValue(bootString(d)) }
+        }
+    }
+    if !f.BootDevice.IsNull() && len(fw.BootOrder) > 0 {
+        want, err := bootDevices(m)
+        if err != nil || len(want) == 0 || !sameBootDevice(want[0], fw.BootOrder[0]) { f.BootDevice = types.StringValue(bootString(fw.BootOrder[0])) }
+    }
+    if !f.NetworkBootProtocol.IsNull() && fw.PreferredNetworkBootProtocol != "" && !strings.EqualFold(f.NetworkBootProtocol.ValueString(), fw.PreferredNetworkBootProtocol) {
+        f.NetworkBootProtocol = types.StringValue(fw.PreferredNetworkBootProtocol)
+    }
+    if !f.PauseAfterBootFailure.IsNull() && fw.PauseAfterBootFailure != nil { f.PauseAfterBootFailure = types.BoolValue(*fw.PauseAfterBootFailure) }
+}
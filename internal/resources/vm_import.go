@@ -0,0 +1,119 @@
+package resources
+
+import (
+    "context"
+    "regexp"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+var _ resource.ResourceWithImportState = &VMResource{}
+
+var guidRe = regexp.MustCompile(`^\{?[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\}?$`)
+
+// ImportState accepts a VM name or Hyper-V VM ID and builds a complete state from the host, so a
+// matching configuration plans with no diff. Imported disks are protected: Terraform did not
+// create them and must not delete them.
+func (r *VMResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+    if r.cl == nil {
+        resp.Diagnostics.AddError("provider not configured", "client missing")
+        return
+    }
+    id := strings.TrimSpace(req.ID)
+    if id == "" {
+        resp.Diagnostics.AddError("import failed", "Expected a VM name or VM ID, e.g. terraform import hypervapiv2_vm.web web-01")
+        return
+    }
+    vm, err := r.findVm(ctx, id)
+    if err != nil {
+        if client.IsNotFound(err) {
+            resp.Diagnostics.AddError("import failed", "No VM named or with ID "+id+" is visible to the caller.")
+            return
+        }
+        resp.Diagnostics.AddError("import failed", client.Detail(err))
+        return
+    }
+    m := r.importModel(ctx, vm, &resp.Diagnostics)
+    if resp.Diagnostics.HasError() { return }
+    resp.Diagnostics.Append(resp.State.Set(ctx, &m)...)
+}
+
+// findVm resolves id as a VM ID first when it looks like a GUID, then as a name.
+func (r *VMResource) findVm(ctx context.Context, id string) (*client.Vm, error) {
+    if guidRe.MatchString(id) {
+        want := strings.Trim(id, "{}")
+        vms, err := r.cl.ListVms(ctx)
+        if err != nil { return nil, err }
+        for i := range vms {
+            if strings.EqualFold(vms[i].Identifier(), want) { return r.cl.GetVm(ctx, vms[i].Name) }
+        }
+    }
+    return r.cl.GetVm(ctx, id)
+}
+
+func (r *VMResource) importModel(ctx context.Context, vm *client.Vm, diags *diag.Diagnostics) vmModel {
+    m := vmModel{
+        ID:   types.StringValue(vm.Identifier()),
+        Name: types.StringValue(vm.Name),
+        Timeouts: nullTimeouts(),
+    }
+    if m.ID.ValueString() == "" { m.ID = m.Name }
+    if vm.Generation > 0 { m.Generation = types.Int64Value(int64(vm.Generation)) }
+    r.importNics(ctx, vm, &m, diags)
+    if mc, err := r.cl.GetVmMemoryConfig(ctx, vm.Name); err == nil { m.DynamicMemory = importDynamic(mc) }
+    if p := vm.PowerState(); powerStates[p] { m.Power = types.StringValue(p) }
+
+    // Optional-only attributes are refreshed only when non-null: seed the ones the host reports
+    if vm.Generation != 1 {
+        fw := vm.Firmware
+        if fw == nil { fw, _ = r.cl.GetFirmware(ctx, vm.Name) }
+        if fw != nil && fw.SecureBoot != nil {
+            m.Firmware = &firmwareModel{SecureBoot: types.BoolValue(*fw.SecureBoot), SecureBootTemplate: types.StringNull()}
+            if fw.SecureBootTemplate != "" { m.Firmware.SecureBootTemplate = types.StringValue(fw.SecureBootTemplate) }
+        }
+        sec := vm.Security
+        if sec == nil { sec, _ = r.cl.GetSecurity(ctx, vm.Name) }
+        if sec != nil && (sec.TPM != nil || sec.EncryptionSupport != nil) {
+            m.Security = &securityModel{}
+            if sec.TPM != nil { m.Security.TPM = types.BoolValue(*sec.TPM) }
+            if sec.EncryptionSupport != nil { m.Security.Encrypt = types.BoolValue(*sec.EncryptionSupport) }
+        }
+    }
+
+    attached, err := r.cl.ListAttachedDisks(ctx, vm.Name)
+    if err != nil {
+        diags.AddError("import failed", "Could not list attached disks: "+client.Detail(err))
+        return m
+    }
+    for i := range attached {
+        d := diskModel{Path: types.StringValue(attached[i].Path), Protect: types.BoolValue(true)}
+        applySlot(&d, &attached[i])
+        m.Disks = append(m.Disks, d)
+    }
+    // cpu and memory are filled by the Read that follows import
+    return m
+}
+
+// importNics records a single plain adapter as switch_name, matching the common configuration;
+// several adapters, a VLAN or a static MAC become network_interface blocks.
+func (r *VMResource) importNics(ctx context.Context, vm *client.Vm, m *vmModel, diags *diag.Diagnostics) {
+    adapters, err := r.cl.ListNetworkAdapters(ctx, vm.Name)
+    if err != nil {
+        diags.AddWarning("network adapter import failed", "Falling back to switch_name: "+client.Detail(err))
+        adapters = nil
+    }
+    plain := len(adapters) <= 1
+    for _, a := range adapters {
+        if (a.DynamicMac != nil && !*a.DynamicMac) || (a.VlanID != nil && *a.VlanID != 0) { plain = false }
+    }
+    if plain {
+        if vm.SwitchName != "" { m.SwitchName = types.StringValue(vm.SwitchName) }
+        return
+    }
+    for i := range adapters { m.NetworkInterfaces = append(m.NetworkInterfaces, nicFromAdapter(&adapters[i])) }
+}
@@ -0,0 +1,139 @@
+package resources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/tfsdk"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+	"github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+const (
+	osVhd   = `D:\VMs\web01\os.vhdx`
+	dataVhd = `D:\VMs\web01\data.vhdx`
+)
+
+// fakeHost answers the GETs import makes for web01 with two attached disks; everything else is 404.
+func fakeHost(t *testing.T) *VMResource {
+	t.Helper()
+	routes := map[string]string{
+		"/api/v2/vms/web01":          `{"name":"web01","vmId":"6f1b3c1e-0000-4000-8000-000000000001","state":"Off","generation":2,"cpuCount":2,"memoryMB":2048}`,
+		"/api/v2/vms/web01/adapters": `[]`,
+		"/api/v2/vms/web01/disks/attached": `[{"path":"D:\\VMs\\web01\\os.vhdx","controllerType":"SCSI","controllerNumber":0,"controllerLocation":0},` +
+			`{"path":"D:\\VMs\\web01\\data.vhdx","controllerType":"SCSI","controllerNumber":0,"controllerLocation":1}]`,
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := routes[r.URL.Path]
+		w.Header().Set("Content-Type", "application/json")
+		if !ok || r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":"not found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(ts.Close)
+	cl, err := client.New(client.Config{Endpoint: ts.URL})
+	if err != nil { t.Fatal(err) }
+	return &VMResource{cl: cl}
+}
+
+func importWeb01(t *testing.T, r *VMResource) vmModel {
+	t.Helper()
+	ctx := context.Background()
+	vm, err := r.cl.GetVm(ctx, "web01")
+	if err != nil { t.Fatal(err) }
+	var diags diag.Diagnostics
+	m := r.importModel(ctx, vm, &diags)
+	if diags.HasError() { t.Fatalf("import: %v", diags) }
+	return m
+}
+
+// planAgainst runs ModifyPlan for cfg on top of state, passing configuration through as the
+// proposed plan, and returns the planned model.
+func planAgainst(t *testing.T, r *VMResource, state, cfg vmModel) vmModel {
+	t.Helper()
+	ctx := context.Background()
+	var sr resource.SchemaResponse
+	r.Schema(ctx, resource.SchemaRequest{}, &sr)
+	st := tfsdk.State{Schema: sr.Schema}
+	if d := st.Set(ctx, &state); d.HasError() { t.Fatalf("state: %v", d) }
+	cs := tfsdk.State{Schema: sr.Schema}
+	if d := cs.Set(ctx, &cfg); d.HasError() { t.Fatalf("config: %v", d) }
+	req := resource.ModifyPlanRequest{
+		Config: tfsdk.Config{Schema: sr.Schema, Raw: cs.Raw},
+		Plan:   tfsdk.Plan{Schema: sr.Schema, Raw: cs.Raw},
+		State:  st,
+	}
+	resp := &resource.ModifyPlanResponse{Plan: req.Plan}
+	r.ModifyPlan(ctx, req, resp)
+	if resp.Diagnostics.HasError() { t.Fatalf("plan: %v", resp.Diagnostics) }
+	var out vmModel
+	if d := resp.Plan.Get(ctx, &out); d.HasError() { t.Fatalf("planned: %v", d) }
+	return out
+}
+
+func web01Config(disks ...diskModel) vmModel {
+	return vmModel{Name: types.StringValue("web01"), Disks: disks, Timeouts: nullTimeouts()}
+}
+
+func TestImportedDisksPlanCleanWithNames(t *testing.T) {
+	r := fakeHost(t)
+	state := importWeb01(t, r)
+	cases := map[string]vmModel{
+		"names only": web01Config(
+			diskModel{Name: types.StringValue("os"), Boot: types.BoolValue(true)},
+			diskModel{Name: types.StringValue("data")},
+		),
+		"names and paths": web01Config(
+			diskModel{Name: types.StringValue("os"), Path: types.StringValue(osVhd)},
+			diskModel{Name: types.StringValue("data"), Path: types.StringValue(dataVhd)},
+		),
+		"names, reordered": web01Config(
+			diskModel{Name: types.StringValue("data"), Path: types.StringValue(dataVhd)},
+			diskModel{Name: types.StringValue("os"), Path: types.StringValue(osVhd)},
+		),
+	}
+	for name, cfg := range cases {
+		t.Run(name, func(t *testing.T) {
+			plan := planAgainst(t, r, state, cfg)
+			for i := range plan.Disks {
+				d := &plan.Disks[i]
+				if !d.Protect.ValueBool() { t.Errorf("%s: protect = %v, want true from import", diskLabel(d), d.Protect) }
+				if d.Path.ValueString() == "" { t.Errorf("%s: path not kept from state", diskLabel(d)) }
+			}
+			diff := diffDisks(state.Disks, plan.Disks, 2)
+			if len(diff.added) != 0 || len(diff.removed) != 0 || len(diff.changed) != 0 {
+				t.Errorf("diff = %+v, want none", diff)
+			}
+		})
+	}
+}
+
+func TestImportedDiskAttachedBySourcePath(t *testing.T) {
+	r := fakeHost(t)
+	state := importWeb01(t, r)
+	cfg := web01Config(
+		diskModel{Name: types.StringValue("os"), SourcePath: types.StringValue(`d:/vms/web01/OS.vhdx`)},
+		diskModel{Name: types.StringValue("data"), SourcePath: types.StringValue(dataVhd)},
+	)
+	plan := planAgainst(t, r, state, cfg)
+	diff := diffDisks(state.Disks, plan.Disks, 2)
+	if len(diff.added) != 0 || len(diff.removed) != 0 || len(diff.changed) != 0 {
+		t.Errorf("diff = %+v, want the imported disks kept", diff)
+	}
+
+	// A source_path naming another file is still a different disk
+	cfg.Disks[1].SourcePath = types.StringValue(`D:\VMs\shared\data.vhdx`)
+	plan = planAgainst(t, r, state, cfg)
+	diff = diffDisks(state.Disks, plan.Disks, 2)
+	if len(diff.added) != 1 || diff.added[0] != 1 || len(diff.removed) != 1 || diff.removed[0].Path.ValueString() != dataVhd {
+		t.Errorf("diff = %+v, want data replaced", diff)
+	}
+}
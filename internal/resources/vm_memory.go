@@ -0,0 +1,126 @@
+package resources
+
+import (
+    "context"
+    "fmt"
+    "strconv"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// applyMemoryDefaults turns dynamic memory on when the block is present without enabled.
+func applyMemoryDefaults(ctx context.Context, req resource.ModifyPlanRequest, m *vmModel) {
+    if m.DynamicMemory == nil { return }
+    if configNull(ctx, req, path.Root("dynamic_memory").AtName("enabled")) { m.DynamicMemory.Enabled = types.BoolValue(true) }
+}
+
+// keepMemorySpelling keeps the state spelling of memory and the dynamic memory bounds when the plan
+// only writes them differently, e.g. "2GB" in configuration after import recorded "2048MB".
+func keepMemorySpelling(plan, state *vmModel) {
+    if state == nil { return }
+    plan.Memory = priorSpelling(plan.Memory, state.Memory)
+    if p, s := plan.DynamicMemory, state.DynamicMemory; p != nil && s != nil {
+        p.Minimum = priorSpelling(p.Minimum, s.Minimum)
+        p.Maximum = priorSpelling(p.Maximum, s.Maximum)
+    }
+}
+
+// validateMemory enforces minimum <= memory <= maximum and the host's buffer and weight ranges.
+func validateMemory(m *vmModel, diags *diag.Diagnostics) {
+    dm := m.DynamicMemory
+    if dm == nil { return }
+    at := path.Root("dynamic_memory")
+    size := func(name string, v types.String) (int, bool) {
+        if v.IsNull() || v.IsUnknown() { return 0, false }
+        mb, ok := toMB(v.ValueString())
+        if !ok || mb <= 0 {
+            diags.AddAttributeError(at.AtName(name), "invalid memory size", strconv.Quote(v.ValueString())+" is not a size like 512MB or 8GB.")
+            return 0, false
+        }
+        return mb, true
+    }
+    minMB, hasMin := size("minimum", dm.Minimum)
+    maxMB, hasMax := size("maximum", dm.Maximum)
+    startup, hasStartup := 0, false
+    if !m.Memory.IsUnknown() { startup, hasStartup = toMB(m.Memory.ValueString()) }
+    if hasMin && hasMax && minMB > maxMB {
+        diags.AddAttributeError(at.AtName("minimum"), "invalid dynamic memory range", fmt.Sprintf("minimum (%d MB) is above maximum (%d MB).", minMB, maxMB))
+    }
+    if hasMin && hasStartup && minMB > startup {
+        diags.AddAttributeError(at.AtName("minimum"), "invalid dynamic memory range", fmt.Sprintf("minimum (%d MB) is above memory (%d MB); the VM starts with memory and can only shrink to minimum.", minMB, startup))
+    }
+    if hasMax && hasStartup && maxMB < startup {
+        diags.AddAttributeError(at.AtName("maximum"), "invalid dynamic memory range", fmt.Sprintf("maximum (%d MB) is below memory (%d MB).", maxMB, startup))
+    }
+    if v := dm.BufferPercent.ValueInt64(); !dm.BufferPercent.IsNull() && !dm.BufferPercent.IsUnknown() && (v < 5 || v > 2000) {
+        diags.AddAttributeError(at.AtName("buffer_percent"), "invalid memory buffer", fmt.Sprintf("buffer_percent %d is outside 5-2000.", v))
+    }
+    if v := dm.Priority.ValueInt64(); !dm.Priority.IsNull() && !dm.Priority.IsUnknown() && (v < 0 || v > 100) {
+        diags.AddAttributeError(at.AtName("priority"), "invalid memory weight", fmt.Sprintf("priority %d is outside 0-100.", v))
+    }
+}
+
+// dynamicRequest copies the managed dynamic memory settings into req. Unset fields are left to
+// the host.
+func dynamicRequest(dm *dynamicMemoryModel, req *client.SetVmMemoryRequest) {
+    if dm == nil { return }
+    on := dm.Enabled.IsNull() || dm.Enabled.IsUnknown() || dm.Enabled.ValueBool()
+    req.Dynamic = &on
+    if !on { return }
+    if mb, ok := toMB(dm.Minimum.ValueString()); ok { req.MinMB = &mb }
+    if mb, ok := toMB(dm.Maximum.ValueString()); ok { req.MaxMB = &mb }
+    if !dm.BufferPercent.IsNull() && !dm.BufferPercent.IsUnknown() { v := int(dm.BufferPercent.ValueInt64()); req.BufferPercent = &v }
+    if !dm.Priority.IsNull() && !dm.Priority.IsUnknown() { v := int(dm.Priority.ValueInt64()); req.Priority = &v }
+}
+
+// dynamicChanged reports whether the plan changes dynamic memory. Removing the block leaves the
+// host settings as they are; set enabled = false to return to static memory.
+func dynamicChanged(plan, state *vmModel) bool {
+    p, s := plan.DynamicMemory, state.DynamicMemory
+    if p == nil { return false }
+    if s == nil { return true }
+    sameSize := func(a, b types.String) bool {
+        am, aok := toMB(a.ValueString())
+        bm, bok := toMB(b.ValueString())
+        if aok && bok { return am == bm }
+        return a.ValueString() == b.ValueString()
+    }
+    return !p.Enabled.Equal(s.Enabled) || !sameSize(p.Minimum, s.Minimum) || !sameSize(p.Maximum, s.Maximum) ||
+        !p.BufferPercent.Equal(s.BufferPercent) || !p.Priority.Equal(s.Priority)
+}
+
+// dynamicToggled reports whether dynamic memory is switched on or off, which Hyper-V only allows
+// with the VM off.
+func dynamicToggled(plan, state *vmModel) bool {
+    if plan.DynamicMemory == nil || plan.DynamicMemory.Enabled.IsUnknown() { return false }
+    was := state.DynamicMemory != nil && state.DynamicMemory.Enabled.ValueBool()
+    return plan.DynamicMemory.Enabled.ValueBool() != was
+}
+
+// refreshDynamic maps the host memory config onto the managed dynamic_memory attributes.
+func refreshDynamic(dm *dynamicMemoryModel, mc *client.VmMemoryConfig) {
+    if dm == nil || mc == nil { return }
+    dm.Enabled = types.BoolValue(mc.Dynamic)
+    if !mc.Dynamic { return }
+    if !dm.Minimum.IsNull() && mc.MinMB != nil { dm.Minimum = sizeValue(dm.Minimum, *mc.MinMB) }
+    if !dm.Maximum.IsNull() && mc.MaxMB != nil { dm.Maximum = sizeValue(dm.Maximum, *mc.MaxMB) }
+    if !dm.BufferPercent.IsNull() && mc.BufferPercent != nil { dm.BufferPercent = types.Int64Value(int64(*mc.BufferPercent)) }
+    if !dm.Priority.IsNull() && mc.Priority != nil { dm.Priority = types.Int64Value(int64(*mc.Priority)) }
+}
+
+// importDynamic describes the host's dynamic memory settings as a dynamic_memory block; nil when
+// the VM uses static memory.
+func importDynamic(mc *client.VmMemoryConfig) *dynamicMemoryModel {
+    if mc == nil || !mc.Dynamic { return nil }
+    dm := &dynamicMemoryModel{Enabled: types.BoolValue(true)}
+    if mc.MinMB != nil { dm.Minimum = types.StringValue(strconv.Itoa(*mc.MinMB) + "MB") }
+    if mc.MaxMB != nil { dm.Maximum = types.StringValue(strconv.Itoa(*mc.MaxMB) + "MB") }
+    if mc.BufferPercent != nil { dm.BufferPercent = types.Int64Value(int64(*mc.BufferPercent)) }
+    if mc.Priority != nil { dm.Priority = types.Int64Value(int64(*mc.Priority)) }
+    return dm
+}
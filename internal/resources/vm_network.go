@@ -0,0 +1,317 @@
+package resources
+
+import (
+    "context"
+    "fmt"
+    "regexp"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+var macRe = regexp.MustCompile(`^[0-9A-F]{12}$`)
+
+// normMac strips separators so 00:15:5D:01:02:03, 00-15-5d-01-02-03 and 00155D010203 compare equal.
+func normMac(s string) string {
+    r := strings.NewReplacer(":", "", "-", "", ".", "")
+    return strings.ToUpper(r.Replace(strings.TrimSpace(s)))
+}
+
+// macValue returns prior when it already denotes mac, else mac as reported. Hyper-V reports a
+// dynamic MAC as all zeros until the VM first starts; that is recorded as null.
+func macValue(prior types.String, mac string) types.String {
+    n := normMac(mac)
+    if n == "" || strings.Trim(n, "0") == "" { return types.StringNull() }
+    if !prior.IsUnknown() && normMac(prior.ValueString()) == n { return prior }
+    return types.StringValue(n)
+}
+
+// vlanValue maps the host VLAN to state: untagged (nil or 0) is null.
+func vlanValue(v *int) types.Int64 {
+    if v == nil || *v == 0 { return types.Int64Null() }
+    return types.Int64Value(int64(*v))
+}
+
+// applyNicDefaults names new interfaces, defaults is_connected to true and keeps the MAC and name
+// recorded for interfaces already in state.
+func applyNicDefaults(ctx context.Context, req resource.ModifyPlanRequest, m, state *vmModel) {
+    taken := map[string]bool{}
+    for i := range m.NetworkInterfaces {
+        if n := m.NetworkInterfaces[i].Name.ValueString(); n != "" { taken[strings.ToLower(n)] = true }
+    }
+    if state != nil {
+        for _, n := range state.NetworkInterfaces { taken[strings.ToLower(n.Name.ValueString())] = true }
+    }
+    for i := range m.NetworkInterfaces {
+        n := &m.NetworkInterfaces[i]
+        at := path.Root("network_interface").AtListIndex(i)
+        prior := priorNic(state, i, n)
+        if configNull(ctx, req, at.AtName("is_connected")) { n.IsConnected = types.BoolValue(true) }
+        if configNull(ctx, req, at.AtName("mac_address")) {
+            if prior != nil { n.MacAddress = prior.MacAddress }
+        }
+        if !configNull(ctx, req, at.AtName("name")) { continue }
+        if prior != nil {
+            n.Name = prior.Name
+            continue
+        }
+        // A stable name is what keys the adapter on later updates; Hyper-V names every adapter
+        // "Network Adapter" by default
+        for k := i; ; k++ {
+            name := fmt.Sprintf("nic%d", k)
+            if !taken[name] {
+                taken[name] = true
+                n.Name = types.StringValue(name)
+                break
+            }
+        }
+    }
+}
+
+// priorNic finds the state entry for planned interface i: by name when named, else by static MAC,
+// else by position.
+func priorNic(state *vmModel, i int, n *networkInterfaceModel) *networkInterfaceModel {
+    if state == nil { return nil }
+    if name := n.Name.ValueString(); name != "" {
+        for j := range state.NetworkInterfaces {
+            if strings.EqualFold(state.NetworkInterfaces[j].Name.ValueString(), name) { return &state.NetworkInterfaces[j] }
+        }
+        return nil
+    }
+    if mac := normMac(n.MacAddress.ValueString()); mac != "" && !n.MacAddress.IsUnknown() {
+        for j := range state.NetworkInterfaces {
+            if normMac(state.NetworkInterfaces[j].MacAddress.ValueString()) == mac { return &state.NetworkInterfaces[j] }
+        }
+    }
+    if i < len(state.NetworkInterfaces) { return &state.NetworkInterfaces[i] }
+    return nil
+}
+
+// validateNics rejects configurations the host would fail on mid-apply.
+func validateNics(m *vmModel, diags *diag.Diagnostics) {
+    if len(m.NetworkInterfaces) == 0 { return }
+    if m.SwitchName.ValueString() != "" {
+        diags.AddAttributeError(path.Root("switch_name"), "conflicting network configuration",
+            "switch_name creates a single default adapter; describe every adapter with network_interface blocks instead.")
+    }
+    seen := map[string]bool{}
+    for i, n := range m.NetworkInterfaces {
+        at := path.Root("network_interface").AtListIndex(i)
+        if name := strings.ToLower(n.Name.ValueString()); name != "" {
+            if seen[name] { diags.AddAttributeError(at.AtName("name"), "duplicate network interface", "Adapter names must be unique within a VM: "+n.Name.ValueString()) }
+            seen[name] = true
+        }
+        if mac := n.MacAddress.ValueString(); mac != "" && !n.MacAddress.IsUnknown() && !macRe.MatchString(normMac(mac)) {
+            diags.AddAttributeError(at.AtName("mac_address"), "invalid MAC address", mac+" is not 12 hex digits, e.g. 00155D010203 or 00:15:5D:01:02:03.")
+        }
+        if v := n.VlanID.ValueInt64(); !n.VlanID.IsNull() && !n.VlanID.IsUnknown() && (v < 1 || v > 4094) {
+            diags.AddAttributeError(at.AtName("vlan_id"), "invalid VLAN ID", fmt.Sprintf("vlan_id %d is outside 1-4094; omit it for an untagged adapter.", v))
+        }
+    }
+}
+
+// nicDiff keys interfaces by name, which applyNicDefaults has settled for every planned entry.
+type nicDiff struct {
+    added   []int // plan indexes
+    removed []networkInterfaceModel
+    changed [][2]int // plan index, state index
+}
+
+func diffNics(state, plan []networkInterfaceModel) nicDiff {
+    var out nicDiff
+    prior := map[string]int{}
+    for j := range state { prior[strings.ToLower(state[j].Name.ValueString())] = j }
+    matched := map[int]bool{}
+    for i := range plan {
+        j, ok := prior[strings.ToLower(plan[i].Name.ValueString())]
+        if !ok || matched[j] {
+            out.added = append(out.added, i)
+            continue
+        }
+        matched[j] = true
+        out.changed = append(out.changed, [2]int{i, j})
+    }
+    for j := range state {
+        if !matched[j] { out.removed = append(out.removed, state[j]) }
+    }
+    return out
+}
+
+// macChanged reports whether the plan pins a static MAC different from the adapter's current one.
+func macChanged(plan, prior *networkInterfaceModel) bool {
+    if plan.MacAddress.IsUnknown() || plan.MacAddress.IsNull() { return false }
+    return normMac(plan.MacAddress.ValueString()) != normMac(prior.MacAddress.ValueString())
+}
+
+// nicOffline lists interface changes the VM must be off for: adding or removing adapters on a
+// Generation 1 VM, and changing an adapter's MAC.
+func nicOffline(plan, state *vmModel) []string {
+    diff := diffNics(state.NetworkInterfaces, plan.NetworkInterfaces)
+    gen1 := generation(plan) == 1
+    var out []string
+    for _, n := range diff.removed {
+        if gen1 { out = append(out, "remove adapter "+n.Name.ValueString()) }
+    }
+    for _, i := range diff.added {
+        if gen1 { out = append(out, "add adapter "+plan.NetworkInterfaces[i].Name.ValueString()) }
+    }
+    for _, c := range diff.changed {
+        if macChanged(&plan.NetworkInterfaces[c[0]], &state.NetworkInterfaces[c[1]]) {
+            out = append(out, "change MAC of adapter "+plan.NetworkInterfaces[c[0]].Name.ValueString())
+        }
+    }
+    return out
+}
+
+func addNicRequest(n *networkInterfaceModel) client.AddNetworkAdapterRequest {
+    name := n.Name.ValueString()
+    connected := n.IsConnected.IsNull() || n.IsConnected.ValueBool()
+    req := client.AddNetworkAdapterRequest{Name: &name, IsConnected: &connected}
+    if sw := n.Switch.ValueString(); sw != "" && connected { req.SwitchName = &sw }
+    if mac := normMac(n.MacAddress.ValueString()); mac != "" && !n.MacAddress.IsUnknown() { req.MacAddress = &mac }
+    if !n.VlanID.IsNull() && !n.VlanID.IsUnknown() { v := int(n.VlanID.ValueInt64()); req.VlanID = &v }
+    return req
+}
+
+// addNics creates the adapters at the given plan indexes.
+func (r *VMResource) addNics(ctx context.Context, name string, m *vmModel, idx []int, diags *diag.Diagnostics) {
+    for _, i := range idx {
+        n := &m.NetworkInterfaces[i]
+        out, err := r.cl.AddNetworkAdapter(ctx, name, addNicRequest(n))
+        if err != nil {
+            diags.AddAttributeError(path.Root("network_interface").AtListIndex(i), "network adapter add failed", client.Detail(err))
+            return
+        }
+        if out != nil && out.MacAddress != "" { n.MacAddress = macValue(n.MacAddress, out.MacAddress) }
+    }
+}
+
+// recordNics reads back the MAC of adapters whose MAC is still unknown (dynamic MACs on create).
+func (r *VMResource) recordNics(ctx context.Context, m *vmModel, diags *diag.Diagnostics) {
+    pending := false
+    for _, n := range m.NetworkInterfaces {
+        if n.MacAddress.IsUnknown() { pending = true }
+    }
+    if !pending { return }
+    adapters, err := r.cl.ListNetworkAdapters(ctx, m.Name.ValueString())
+    if err != nil {
+        diags.AddWarning("network adapter read failed", "MAC addresses will be recorded on the next refresh: "+client.Detail(err))
+        return
+    }
+    for i := range m.NetworkInterfaces {
+        n := &m.NetworkInterfaces[i]
+        if !n.MacAddress.IsUnknown() { continue }
+        for _, a := range adapters {
+            if strings.EqualFold(a.Name, n.Name.ValueString()) { n.MacAddress = macValue(n.MacAddress, a.MacAddress); break }
+        }
+    }
+}
+
+// reconcileNics applies the interface list difference in place: remove adapters dropped from
+// configuration, re-create adapters whose static MAC changed, move, connect or disconnect adapters
+// and set VLANs, then add new adapters.
+func (r *VMResource) reconcileNics(ctx context.Context, off *offlineSession, plan, state *vmModel, diags *diag.Diagnostics) {
+    diff := diffNics(state.NetworkInterfaces, plan.NetworkInterfaces)
+    name := state.Name.ValueString()
+    gen1 := generation(plan) == 1
+    stopFor := func(what string) bool {
+        if err := off.stop(ctx); err != nil {
+            diags.AddError("stop for "+what+" failed", client.Detail(err))
+            return false
+        }
+        return true
+    }
+
+    for _, n := range diff.removed {
+        if gen1 && !stopFor("adapter removal") { return }
+        if err := r.cl.DeleteAdapter(ctx, name, n.Name.ValueString()); err != nil && !client.IsNotFound(err) {
+            diags.AddError("network adapter remove failed", n.Name.ValueString()+": "+client.Detail(err))
+            return
+        }
+    }
+
+    var readd []int
+    for _, c := range diff.changed {
+        n, prior := &plan.NetworkInterfaces[c[0]], &state.NetworkInterfaces[c[1]]
+        at := path.Root("network_interface").AtListIndex(c[0])
+        adapter := n.Name.ValueString()
+        if macChanged(n, prior) {
+            // Hyper-V only changes a MAC with the VM off; re-creating the adapter applies every setting
+            if !stopFor("MAC change") { return }
+            if err := r.cl.DeleteAdapter(ctx, name, adapter); err != nil && !client.IsNotFound(err) {
+                diags.AddAttributeError(at.AtName("mac_address"), "MAC change failed", client.Detail(err))
+                return
+            }
+            readd = append(readd, c[0])
+            continue
+        }
+        connected := n.IsConnected.ValueBool()
+        switch {
+        case connected && (!prior.IsConnected.ValueBool() || !strings.EqualFold(n.Switch.ValueString(), prior.Switch.ValueString())):
+            if err := r.cl.ConnectAdapter(ctx, name, adapter, n.Switch.ValueString()); err != nil {
+                diags.AddAttributeError(at.AtName("switch"), "network adapter connect failed", client.Detail(err))
+                return
+            }
+        case !connected && prior.IsConnected.ValueBool():
+            if err := r.cl.DisconnectAdapter(ctx, name, adapter); err != nil {
+                diags.AddAttributeError(at.AtName("is_connected"), "network adapter disconnect failed", client.Detail(err))
+                return
+            }
+        }
+        if !n.VlanID.Equal(prior.VlanID) {
+            if err := r.cl.SetAdapterVlan(ctx, name, adapter, int(n.VlanID.ValueInt64())); err != nil {
+                diags.AddAttributeError(at.AtName("vlan_id"), "VLAN update failed", client.Detail(err))
+                return
+            }
+        }
+    }
+    r.addNics(ctx, name, plan, readd, diags)
+    if diags.HasError() { return }
+
+    if len(diff.added) == 0 { return }
+    if gen1 && !stopFor("adapter add") { return }
+    r.addNics(ctx, name, plan, diff.added, diags)
+    if diags.HasError() { return }
+    r.recordNics(ctx, plan, diags)
+}
+
+// refreshNics maps the host adapters onto managed interfaces: adapters that disappeared are
+// dropped, unknown adapters are appended so they show up for removal, and a disconnected
+// adapter keeps its configured switch.
+func refreshNics(nics []networkInterfaceModel, adapters []client.NetworkAdapter) []networkInterfaceModel {
+    byName := map[string]*client.NetworkAdapter{}
+    for i := range adapters { byName[strings.ToLower(adapters[i].Name)] = &adapters[i] }
+    seen := map[string]bool{}
+    out := make([]networkInterfaceModel, 0, len(nics))
+    for _, n := range nics {
+        key := strings.ToLower(n.Name.ValueString())
+        a, ok := byName[key]
+        if !ok { continue }
+        seen[key] = true
+        if a.SwitchName != "" { n.Switch = types.StringValue(a.SwitchName) }
+        n.IsConnected = types.BoolValue(a.IsConnected)
+        n.MacAddress = macValue(n.MacAddress, a.MacAddress)
+        n.VlanID = vlanValue(a.VlanID)
+        out = append(out, n)
+    }
+    for _, a := range adapters {
+        if seen[strings.ToLower(a.Name)] { continue }
+        out = append(out, nicFromAdapter(&a))
+    }
+    return out
+}
+
+func nicFromAdapter(a *client.NetworkAdapter) networkInterfaceModel {
+    return networkInterfaceModel{
+        Name:        types.StringValue(a.Name),
+        Switch:      types.StringValue(a.SwitchName),
+        MacAddress:  macValue(types.StringNull(), a.MacAddress),
+        IsConnected: types.BoolValue(a.IsConnected),
+        VlanID:      vlanValue(a.VlanID),
+    }
+}
@@ -0,0 +1,91 @@
+package resources
+
+import (
+    "context"
+    "encoding/json"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+)
+
+// ownedDisksKey is the private state key recording which disk files this resource created.
+const ownedDisksKey = "owned_disks"
+
+// diskOwnership separates files the provider created (new or cloned), which delete_disks may
+// remove, from files it only attached, which are never deleted.
+type diskOwnership struct {
+    Created  []string `json:"created"`
+    Attached []string `json:"attached"`
+    // recorded is false for state written before ownership was tracked
+    recorded bool
+}
+
+type privateGetter interface {
+    GetKey(ctx context.Context, key string) ([]byte, diag.Diagnostics)
+}
+
+type privateSetter interface {
+    SetKey(ctx context.Context, key string, value []byte) diag.Diagnostics
+}
+
+func readOwnership(ctx context.Context, p privateGetter, diags *diag.Diagnostics) *diskOwnership {
+    o := &diskOwnership{}
+    if p == nil { return o }
+    b, d := p.GetKey(ctx, ownedDisksKey)
+    diags.Append(d...)
+    if len(b) == 0 { return o }
+    if err := json.Unmarshal(b, o); err != nil {
+        diags.AddWarning("disk ownership unreadable", "No disk files will be deleted: "+err.Error())
+        return &diskOwnership{}
+    }
+    o.recorded = true
+    return o
+}
+
+func writeOwnership(ctx context.Context, p privateSetter, o *diskOwnership, diags *diag.Diagnostics) {
+    b, err := json.Marshal(o)
+    if err != nil {
+        diags.AddError("disk ownership not saved", err.Error())
+        return
+    }
+    diags.Append(p.SetKey(ctx, ownedDisksKey, b)...)
+}
+
+func (o *diskOwnership) owns(file string) bool {
+    for _, p := range o.Created {
+        if normPath(p) == normPath(file) { return true }
+    }
+    return false
+}
+
+// record notes the files behind jobs once their paths are resolved.
+func (o *diskOwnership) record(jobs []*diskJob) {
+    for _, j := range jobs {
+        if j == nil || j.path == "" { continue }
+        if j.kind == diskAttach {
+            o.Attached = appendPath(o.Attached, j.path)
+        } else {
+            o.Created = appendPath(o.Created, j.path)
+        }
+    }
+    o.recorded = true
+}
+
+// forget drops file once it is deleted or no longer attached.
+func (o *diskOwnership) forget(file string) {
+    drop := func(list []string) []string {
+        out := list[:0]
+        for _, p := range list {
+            if normPath(p) != normPath(file) { out = append(out, p) }
+        }
+        return out
+    }
+    o.Created = drop(o.Created)
+    o.Attached = drop(o.Attached)
+}
+
+func appendPath(list []string, file string) []string {
+    for _, p := range list {
+        if normPath(p) == normPath(file) { return list }
+    }
+    return append(list, file)
+}
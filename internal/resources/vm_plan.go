@@ -0,0 +1,267 @@
+package resources
+
+import (
+    "context"
+    "fmt"
+    "path/filepath"
+    "strconv"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/attr"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/policy"
+)
+
+var _ resource.ResourceWithModifyPlan = &VMResource{}
+
+// ModifyPlan fills unset values from provider defaults and runs the policy checks that would
+// otherwise only surface mid-apply.
+func (r *VMResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+    // Destroy plans and an unconfigured provider have nothing to check
+    if req.Plan.Raw.IsNull() || r.cl == nil { return }
+    var plan vmModel
+    resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+    if resp.Diagnostics.HasError() { return }
+    var state *vmModel
+    if !req.State.Raw.IsNull() {
+        state = &vmModel{}
+        resp.Diagnostics.Append(req.State.Get(ctx, state)...)
+        if resp.Diagnostics.HasError() { return }
+    }
+
+    r.applyDefaults(ctx, req, &plan, state, &resp.Diagnostics)
+    if resp.Diagnostics.HasError() { return }
+    applyMemoryDefaults(ctx, req, &plan)
+    keepMemorySpelling(&plan, state)
+    applyNicDefaults(ctx, req, &plan, state)
+    validateMemory(&plan, &resp.Diagnostics)
+    validateSecurity(&plan, &resp.Diagnostics)
+    validateFirmware(&plan, &resp.Diagnostics)
+    validateDiskSlots(&plan, &resp.Diagnostics)
+    validateCreateFailure(&plan, &resp.Diagnostics)
+    validateNics(&plan, &resp.Diagnostics)
+    validatePower(&plan, &resp.Diagnostics)
+    if resp.Diagnostics.HasError() { return }
+    resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+    if state != nil {
+        planDiskChanges(&plan, state, readOwnership(ctx, req.Private, &resp.Diagnostics), &resp.Diagnostics)
+    }
+
+    if r.cl.EnforcePolicyPaths() {
+        r.validatePaths(ctx, &plan, state, &resp.Diagnostics)
+    }
+    // Disk placement only happens on create
+    if r.cl.Strict() && req.State.Raw.IsNull() {
+        r.previewPlacement(ctx, &plan, &resp.Diagnostics)
+    }
+}
+
+// applyDefaults resolves cpu, memory and disk sizes left unset in configuration: the provider
+// defaults block wins, so changing a default shows up as a diff; otherwise the prior state value is
+// kept; on create without a default the value stays unknown and is read back from the host.
+func (r *VMResource) applyDefaults(ctx context.Context, req resource.ModifyPlanRequest, m *vmModel, state *vmModel, diags *diag.Diagnostics) {
+    def := r.cl.Defaults()
+    if configNull(ctx, req, path.Root("cpu")) {
+        switch {
+        case def.CPU > 0:
+            m.CPU = types.Int64Value(int64(def.CPU))
+        case state != nil:
+            m.CPU = state.CPU
+        }
+    }
+    if configNull(ctx, req, path.Root("memory")) {
+        switch {
+        case def.Memory != "":
+            if _, ok := toMB(def.Memory); !ok {
+                diags.AddError("invalid provider default", "defaults.memory "+strconv.Quote(def.Memory)+" is not a size like 2048MB or 2GB.")
+                return
+            }
+            m.Memory = types.StringValue(def.Memory)
+        case state != nil:
+            m.Memory = state.Memory
+        }
+    }
+    osDisk := osDiskIndex(m.Disks)
+    for i := range m.Disks {
+        d := &m.Disks[i]
+        prior := priorDisk(state, i, d)
+        if configNull(ctx, req, path.Root("disk").AtListIndex(i).AtName("protect")) {
+            // Imported disks keep protect = true from state; new disks are unprotected
+            if prior != nil && !prior.Protect.IsNull() {
+                d.Protect = prior.Protect
+            } else {
+                d.Protect = types.BoolValue(false)
+            }
+        }
+        if prior != nil {
+            at := path.Root("disk").AtListIndex(i)
+            if configNull(ctx, req, at.AtName("path")) { d.Path = prior.Path }
+            // Unset placement keeps where the disk is attached
+            if configNull(ctx, req, at.AtName("controller")) { d.Controller = prior.Controller }
+            if configNull(ctx, req, at.AtName("controller_number")) { d.ControllerNumber = prior.ControllerNumber }
+            if configNull(ctx, req, at.AtName("lun")) { d.Lun = prior.Lun }
+        }
+        if !configNull(ctx, req, path.Root("disk").AtListIndex(i).AtName("size")) { continue }
+        newDisk := d.CloneFrom.ValueString() == "" && d.SourcePath.ValueString() == ""
+        if i == osDisk && newDisk && def.Disk != "" {
+            if _, ok := toMB(def.Disk); !ok {
+                diags.AddError("invalid provider default", "defaults.disk "+strconv.Quote(def.Disk)+" is not a size like 40GB.")
+                return
+            }
+            d.Size = types.StringValue(def.Disk)
+            continue
+        }
+        if prior != nil {
+            d.Size = prior.Size
+            continue
+        }
+        // Cloned and attached disks take their size from the source
+        d.Size = types.StringNull()
+    }
+}
+
+// configNull reports whether the attribute at p is absent from configuration.
+func configNull(ctx context.Context, req resource.ModifyPlanRequest, p path.Path) bool {
+    var v attr.Value
+    if d := req.Config.GetAttribute(ctx, p, &v); d.HasError() || v == nil { return false }
+    return v.IsNull()
+}
+
+// osDiskIndex returns the disk treated as the OS disk: the first boot or purpose = "os" disk, else
+// the first disk; -1 when there are none.
+func osDiskIndex(disks []diskModel) int {
+    for i := range disks {
+        if disks[i].Boot.ValueBool() || strings.EqualFold(disks[i].Purpose.ValueString(), "os") { return i }
+    }
+    if len(disks) > 0 { return 0 }
+    return -1
+}
+
+// priorDisk finds the state entry for planned disk i (see priorIndex).
+func priorDisk(state *vmModel, i int, d *diskModel) *diskModel {
+    if state == nil { return nil }
+    if j := priorIndex(state.Disks, i, d); j >= 0 { return &state.Disks[j] }
+    return nil
+}
+
+// priorIndex returns the index in state of planned disk i, or -1: by name when named, else by file
+// path when known, else by position. A named disk also matches an unnamed entry for the same file,
+// or at its position when the plan has no file yet, since imported disks are recorded by path only.
+func priorIndex(state []diskModel, i int, d *diskModel) int {
+    p := normPath(diskFile(d))
+    if n := d.Name.ValueString(); n != "" {
+        for j := range state {
+            if state[j].Name.ValueString() == n { return j }
+        }
+        for j := range state {
+            if state[j].Name.ValueString() == "" && p != "" && normPath(diskFile(&state[j])) == p { return j }
+        }
+        if p == "" && i < len(state) && state[i].Name.ValueString() == "" { return i }
+        return -1
+    }
+    if p != "" {
+        for j := range state {
+            if normPath(diskFile(&state[j])) == p { return j }
+        }
+    }
+    if i < len(state) && state[i].Name.ValueString() == "" { return i }
+    return -1
+}
+
+// explicitPath is a user-supplied path and the validate-path operation it is used for.
+type explicitPath struct {
+    at    path.Path
+    value types.String
+    op    string // create | clone | attach
+    prior types.String // the same attribute in state, null on create
+}
+
+// validatePaths checks every explicit path against the effective policy (enforce_policy_paths), so
+// a denied path fails the plan instead of leaving a half-created VM. Paths unchanged from state
+// were accepted by an earlier apply and are not re-checked; disks are matched to state like
+// priorDisk does, so reordering the list does not compare the wrong entries.
+func (r *VMResource) validatePaths(ctx context.Context, m, state *vmModel, diags *diag.Diagnostics) {
+    null := types.StringNull()
+    paths := []explicitPath{
+        {path.Root("new_vhd_path"), m.NewVhdPath, "create", null},
+        {path.Root("parent_path"), m.ParentPath, "attach", null},
+    }
+    if state != nil {
+        paths[0].prior, paths[1].prior = state.NewVhdPath, state.ParentPath
+    }
+    for i := range m.Disks {
+        d := &m.Disks[i]
+        at := path.Root("disk").AtListIndex(i)
+        op := "create"
+        if d.CloneFrom.ValueString() != "" { op = "clone" }
+        prior := &diskModel{Path: null, CloneFrom: null, SourcePath: null, ParentPath: null}
+        if pd := priorDisk(state, i, d); pd != nil { prior = pd }
+        paths = append(paths,
+            explicitPath{at.AtName("path"), d.Path, op, prior.Path},
+            explicitPath{at.AtName("clone_from"), d.CloneFrom, "clone", prior.CloneFrom},
+            explicitPath{at.AtName("source_path"), d.SourcePath, "attach", prior.SourcePath},
+            explicitPath{at.AtName("parent_path"), d.ParentPath, "attach", prior.ParentPath},
+        )
+    }
+    for _, p := range paths {
+        if p.value.IsNull() || p.value.IsUnknown() || p.value.ValueString() == "" { continue }
+        if p.prior.Equal(p.value) { continue }
+        v := p.value.ValueString()
+        out, err := r.cl.ValidatePath(ctx, client.PathValidateRequest{Path: v, Operation: p.op, Ext: strings.TrimPrefix(filepath.Ext(v), ".")})
+        if err != nil {
+            diags.AddAttributeError(p.at, "path validation failed", "Could not validate "+v+" ("+p.op+"): "+client.Detail(err))
+            continue
+        }
+        if out.Allowed && len(out.Violations) == 0 { continue }
+        msg := v + " is not allowed for " + p.op
+        if out.Message != "" { msg += ": " + out.Message }
+        if len(out.Violations) > 0 { msg += "\nViolations: " + strings.Join(out.Violations, "; ") }
+        diags.AddAttributeError(p.at, "path violates policy", msg)
+    }
+}
+
+// previewPlacement asks plan-disk for every auto-placed disk so strict mode can fail the plan on
+// policy warnings, low free space or an unavailable planner instead of applying a degraded placement.
+func (r *VMResource) previewPlacement(ctx context.Context, m *vmModel, diags *diag.Diagnostics) {
+    if m.Name.IsUnknown() { return }
+    order, err := diskOrder(m.Disks)
+    if err != nil {
+        diags.AddAttributeError(path.Root("disk"), "invalid disk placement", err.Error())
+        return
+    }
+    osIdx := osDiskIndex(m.Disks)
+    planned := map[string]string{} // disk name -> previewed path
+    for _, i := range order {
+        d := &m.Disks[i]
+        at := path.Root("disk").AtListIndex(i)
+        if d.Path.ValueString() != "" || d.SourcePath.ValueString() != "" || d.CloneFrom.IsUnknown() || d.Size.IsUnknown() { continue }
+        coLocate := ""
+        if d.Placement != nil { coLocate = planned[d.Placement.CoLocateWith.ValueString()] }
+        req := placementRequest(m.Name.ValueString(), d, diskPurpose(d, i == osIdx), coLocate)
+        out, err := r.cl.PlanDisk(ctx, req)
+        if err != nil {
+            policy.Violation(diags, at, "disk auto-placement failed", "plan-disk is unavailable, so placement cannot be checked: "+client.Detail(err))
+            continue
+        }
+        if n := d.Name.ValueString(); n != "" { planned[n] = out.Path }
+        for _, w := range out.Warnings { policy.Violation(diags, at, "plan-disk warning", w) }
+        if !out.Writable {
+            policy.Violation(diags, at, "plan-disk path not writable", out.Path+" is not writable by the API host.")
+        }
+        if req.MinFreeGB != nil && out.FreeGBAfter < *req.MinFreeGB {
+            policy.Violation(diags, at, "low free space", fmt.Sprintf("%s would leave %d GB free on %s (min_free_gb = %d).", out.Path, out.FreeGBAfter, out.MatchedRoot, *req.MinFreeGB))
+        }
+    }
+}
+
+// diskPurpose returns the declared purpose, defaulting to "os" for the OS disk and "data" otherwise.
+func diskPurpose(d *diskModel, isOS bool) string {
+    if p := strings.TrimSpace(d.Purpose.ValueString()); p != "" { return p }
+    if isOS { return "os" }
+    return "data"
+}
@@ -0,0 +1,159 @@
+package resources
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// powerStates are the power values the resource can drive a VM to.
+var powerStates = map[string]bool{"running": true, "stopped": true, "saved": true, "paused": true}
+
+var stopMethods = map[string]bool{"graceful": true, "force": true, "turnoff": true, "escalate": true}
+
+// validatePower checks power, stop_method and shutdown_timeout_seconds against the values the
+// host understands.
+func validatePower(m *vmModel, diags *diag.Diagnostics) {
+    if v := m.Power.ValueString(); v != "" && !m.Power.IsUnknown() && !powerStates[strings.ToLower(v)] {
+        diags.AddAttributeError(path.Root("power"), "invalid power", v+" is not running, stopped, saved or paused.")
+    }
+    if v := m.StopMethod.ValueString(); v != "" && !m.StopMethod.IsUnknown() && !stopMethods[strings.ToLower(v)] {
+        diags.AddAttributeError(path.Root("stop_method"), "invalid stop_method", v+" is not graceful, force, turnoff or escalate.")
+    }
+    if v := m.ShutdownTimeoutSec.ValueInt64(); !m.ShutdownTimeoutSec.IsNull() && !m.ShutdownTimeoutSec.IsUnknown() && v <= 0 {
+        diags.AddAttributeError(path.Root("shutdown_timeout_seconds"), "invalid shutdown timeout", fmt.Sprintf("shutdown_timeout_seconds must be positive, got %d.", v))
+    }
+}
+
+// applyDesiredPower drives the VM to the planned power state and waits for the host to report it.
+// Saving or pausing a VM that is off starts it first; stopping a saved or paused VM resumes it so
+// the guest can shut down cleanly.
+func (r *VMResource) applyDesiredPower(ctx context.Context, m *vmModel) error {
+    if r.cl == nil || m == nil || m.Name.IsNull() { return nil }
+    desired := strings.ToLower(m.Power.ValueString())
+    if desired == "" { return nil }
+    name := m.Name.ValueString()
+    vm, err := r.cl.GetVm(ctx, name)
+    if err != nil { return err }
+    current := vm.PowerState()
+    if current == desired { return nil }
+    tflog.Info(ctx, "changing VM power state", map[string]any{"vm": name, "from": current, "to": desired})
+
+    if desired == "stopped" {
+        if current == "saved" || current == "paused" {
+            if err := r.bringUp(ctx, m, current); err != nil { return err }
+        }
+        return r.stopVm(ctx, m)
+    }
+    if current != "running" && !(desired == "saved" && current == "paused") {
+        if err := r.bringUp(ctx, m, current); err != nil { return err }
+    }
+    switch desired {
+    case "saved":
+        if err := r.cl.SaveVm(ctx, name); err != nil { return fmt.Errorf("save: %w", err) }
+    case "paused":
+        if err := r.cl.PauseVm(ctx, name); err != nil { return fmt.Errorf("pause: %w", err) }
+    }
+    return r.waitForPower(ctx, name, desired, powerTimeout(m))
+}
+
+// bringUp starts a stopped or saved VM, or resumes a paused one, and waits until it runs.
+func (r *VMResource) bringUp(ctx context.Context, m *vmModel, current string) error {
+    name := m.Name.ValueString()
+    if current == "paused" {
+        if err := r.cl.ResumeVm(ctx, name); err != nil { return fmt.Errorf("resume: %w", err) }
+    } else if err := r.cl.StartVm(ctx, name); err != nil {
+        return fmt.Errorf("start: %w", err)
+    }
+    return r.waitForPower(ctx, name, "running", powerTimeout(m))
+}
+
+// stopSteps returns the stop attempts for stop_method: escalate tries a guest shutdown, then a
+// forced one, then turns the VM off.
+func stopSteps(m *vmModel) []string {
+    switch method := strings.ToLower(m.StopMethod.ValueString()); method {
+    case "force", "turnoff":
+        return []string{method}
+    case "escalate":
+        return []string{"graceful", "force", "turnoff"}
+    }
+    return []string{"graceful"}
+}
+
+// stopVm stops the VM per stop_method and waits for it to power off. Every step but the last waits
+// shutdown_timeout_seconds before the next one is tried; the last waits wait_timeout_seconds.
+func (r *VMResource) stopVm(ctx context.Context, m *vmModel) error {
+    name := m.Name.ValueString()
+    steps := stopSteps(m)
+    for i, step := range steps {
+        final := i == len(steps)-1
+        window := shutdownTimeout(m)
+        if final { window = powerTimeout(m) }
+        tflog.Info(ctx, "stopping VM", map[string]any{"vm": name, "method": step, "wait": window.String()})
+        // A graceful stop can block until the guest is down, so the request shares the step window
+        sctx, cancel := context.WithTimeout(ctx, window)
+        err := r.cl.StopVm(sctx, name, step == "force", step == "turnoff")
+        cancel()
+        if err == nil || (errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil) {
+            err = r.waitForPower(ctx, name, "stopped", window)
+        }
+        if err == nil {
+            tflog.Info(ctx, "VM stopped", map[string]any{"vm": name, "method": step})
+            return nil
+        }
+        if final || ctx.Err() != nil || client.IsNotFound(err) || client.IsUnauthorized(err) || client.IsPolicyDenied(err) {
+            return fmt.Errorf("%s stop: %w", step, err)
+        }
+        tflog.Warn(ctx, "VM did not stop, escalating", map[string]any{"vm": name, "method": step, "next": steps[i+1], "error": client.Detail(err)})
+    }
+    return nil
+}
+
+// waitForPower polls until the host reports the VM in desired. It fails with the last state seen
+// once timeout passes, and with the context error when ctx ends first.
+func (r *VMResource) waitForPower(ctx context.Context, name string, desired string, timeout time.Duration) error {
+    deadline := time.Now().Add(timeout)
+    desired = strings.ToLower(desired)
+    last := "unknown"
+    var pollErr error
+    for {
+        out, err := r.cl.GetVm(ctx, name)
+        switch {
+        case err == nil:
+            last, pollErr = out.PowerState(), nil
+            if last == desired { return nil }
+        case client.IsNotFound(err) || ctx.Err() != nil:
+            return err
+        default:
+            pollErr = err
+        }
+        if !time.Now().Before(deadline) { break }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(2 * time.Second):
+        }
+    }
+    msg := fmt.Sprintf("VM %s is %s, not %s, after %s", name, last, desired, timeout.Round(time.Second))
+    if pollErr != nil { msg += " (last poll failed: " + client.Detail(pollErr) + ")" }
+    return errors.New(msg)
+}
+
+// recordPower replaces m.Power with what the host reports, so a failed transition is not stored as
+// done. m is left alone when the VM cannot be read.
+func (r *VMResource) recordPower(ctx context.Context, m *vmModel) {
+    ctx, cancel := cleanupContext(ctx, 30*time.Second)
+    defer cancel()
+    if vm, err := r.cl.GetVm(ctx, m.Name.ValueString()); err == nil && vm.State != "" {
+        m.Power = types.StringValue(vm.PowerState())
+    }
+}
@@ -0,0 +1,180 @@
+package resources
+
+import (
+    "context"
+    "strconv"
+    "strings"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+func (r *VMResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var data vmModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &data)...)
+	if resp.Diagnostics.HasError() { return }
+	if r.cl == nil {
+		resp.Diagnostics.AddError("provider not configured", "client missing")
+		return
+	}
+	if data.Name.IsNull() || data.Name.ValueString() == "" {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	limit, d := data.Timeouts.Read(ctx, defaultReadTimeout)
+	resp.Diagnostics.Append(d...)
+	if resp.Diagnostics.HasError() { return }
+	ctx, cancel, op := withDeadline(ctx, "read", limit)
+	defer cancel()
+	defer op.report(ctx, &resp.Diagnostics)
+	op.at("reading the VM")
+	vm, err := r.cl.GetVm(ctx, data.Name.ValueString())
+	if err != nil {
+		// VM no longer exists on the host: drop from state so Terraform plans a re-create
+		if client.IsNotFound(err) {
+			resp.State.RemoveResource(ctx)
+			return
+		}
+		resp.Diagnostics.AddError("read failed", client.Detail(err))
+		return
+	}
+	r.refresh(ctx, &data, vm, &resp.Diagnostics)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &data)...)
+}
+
+// refresh maps live host state into m so out-of-band changes show up in plan. Optional-only
+// attributes are refreshed only when they are managed (non-null in state); sizes keep their prior
+// spelling when equal in meaning ("2GB" vs 2048 MB).
+func (r *VMResource) refresh(ctx context.Context, m *vmModel, vm *client.Vm, diags *diag.Diagnostics) {
+    name := m.Name.ValueString()
+    if id := vm.Identifier(); id != "" { m.ID = types.StringValue(id) }
+
+    cpu := vm.CpuCount
+    if cpu == 0 {
+        if pc, err := r.cl.GetVmProcessorConfig(ctx, name); err == nil { cpu = pc.Count }
+    }
+    if cpu > 0 { m.CPU = types.Int64Value(int64(cpu)) }
+    memMB := vm.MemoryMB
+    if memMB == 0 || m.DynamicMemory != nil {
+        mc, err := r.cl.GetVmMemoryConfig(ctx, name)
+        switch {
+        case err == nil:
+            if memMB == 0 { memMB = mc.StartupMB }
+            refreshDynamic(m.DynamicMemory, mc)
+        case m.DynamicMemory != nil && !client.IsNotFound(err):
+            diags.AddWarning("memory refresh failed", client.Detail(err))
+        }
+    }
+    if memMB > 0 { m.Memory = sizeValue(m.Memory, memMB) }
+
+    if !m.Generation.IsNull() && vm.Generation > 0 { m.Generation = types.Int64Value(int64(vm.Generation)) }
+    if !m.SwitchName.IsNull() { m.SwitchName = types.StringValue(vm.SwitchName) }
+    if !m.Power.IsNull() && vm.State != "" { m.Power = types.StringValue(vm.PowerState()) }
+
+    if m.Firmware != nil {
+        fw := vm.Firmware
+        // The VM view carries secure boot only; the boot order comes from the firmware endpoint
+        if fw == nil || managesBoot(m.Firmware) {
+            var err error
+            if fw, err = r.cl.GetFirmware(ctx, name); err != nil && !client.IsNotFound(err) {
+                diags.AddWarning("firmware refresh failed", client.Detail(err))
+            }
+        }
+        if fw != nil {
+            if !m.Firmware.SecureBoot.IsNull() && fw.SecureBoot != nil { m.Firmware.SecureBoot = types.BoolValue(*fw.SecureBoot) }
+            if !m.Firmware.SecureBootTemplate.IsNull() && fw.SecureBootTemplate != "" { m.Firmware.SecureBootTemplate = types.StringValue(fw.SecureBootTemplate) }
+            refreshFirmware(m, fw)
+        }
+    }
+    if m.Security != nil {
+        sec := vm.Security
+        if sec == nil {
+            var err error
+            if sec, err = r.cl.GetSecurity(ctx, name); err != nil && !client.IsNotFound(err) {
+                diags.AddWarning("security refresh failed", client.Detail(err))
+            }
+        }
+        if sec != nil {
+            if !m.Security.TPM.IsNull() && sec.TPM != nil { m.Security.TPM = types.BoolValue(*sec.TPM) }
+            if !m.Security.Encrypt.IsNull() && sec.EncryptionSupport != nil { m.Security.Encrypt = types.BoolValue(*sec.EncryptionSupport) }
+        }
+    }
+
+    if len(m.NetworkInterfaces) > 0 {
+        if adapters, err := r.cl.ListNetworkAdapters(ctx, name); err != nil {
+            diags.AddWarning("network adapter refresh failed", client.Detail(err))
+        } else {
+            m.NetworkInterfaces = refreshNics(m.NetworkInterfaces, adapters)
+        }
+    }
+
+    attached, err := r.cl.ListAttachedDisks(ctx, name)
+    if err != nil {
+        diags.AddWarning("disk refresh failed", client.Detail(err))
+        return
+    }
+    m.Disks = refreshDisks(m.Disks, attached)
+}
+
+// refreshDisks drops disks whose file is no longer attached, records where the others sit, and
+// appends attached disks that state does not know about. Unknown disks are only reported when every managed disk has a recorded
+// path; otherwise an auto-placed disk could not be told apart from an out-of-band one.
+func refreshDisks(disks []diskModel, attached []client.AttachedDisk) []diskModel {
+    live := map[string]*client.AttachedDisk{}
+    for i := range attached { live[normPath(attached[i].Path)] = &attached[i] }
+    seen := map[string]bool{}
+    allKnown := true
+    out := make([]diskModel, 0, len(disks))
+    for _, d := range disks {
+        p := diskFile(&d)
+        if p == "" {
+            allKnown = false
+            out = append(out, d)
+            continue
+        }
+        a := live[normPath(p)]
+        if a == nil { continue }
+        seen[normPath(p)] = true
+        applySlot(&d, a)
+        out = append(out, d)
+    }
+    if !allKnown { return out }
+    for i := range attached {
+        if seen[normPath(attached[i].Path)] { continue }
+        d := diskModel{Path: types.StringValue(attached[i].Path)}
+        applySlot(&d, &attached[i])
+        out = append(out, d)
+    }
+    return out
+}
+
+// diskFile returns the VHD path a disk entry is attached from: path, else source_path.
+func diskFile(d *diskModel) string {
+    if p := d.Path.ValueString(); p != "" { return p }
+    return d.SourcePath.ValueString()
+}
+
+// normPath compares Windows paths case-insensitively and regardless of slash direction.
+func normPath(p string) string {
+    return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(p), "/", "\\"))
+}
+
+// sizeValue returns prior when it already denotes mb, else mb rendered in MB.
+func sizeValue(prior types.String, mb int) types.String {
+    if v, ok := toMB(prior.ValueString()); ok && v == mb && !prior.IsUnknown() { return prior }
+    return types.StringValue(strconv.Itoa(mb) + "MB")
+}
+
+// priorSpelling returns prior when planned denotes the same size in another spelling ("2GB" and
+// "2048MB"), so a refreshed or imported size does not diff against configuration. Terraform accepts
+// a plan that keeps the prior value in place of an equivalent configured one.
+func priorSpelling(planned, prior types.String) types.String {
+    if planned.IsNull() || planned.IsUnknown() || prior.IsNull() || prior.IsUnknown() { return planned }
+    a, aok := toMB(planned.ValueString())
+    b, bok := toMB(prior.ValueString())
+    if aok && bok && a == b { return prior }
+    return planned
+}
@@ -0,0 +1,68 @@
+package resources
+
+import (
+    "context"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+// validateSecurity fails the plan for settings Generation 1 VMs cannot have.
+func validateSecurity(m *vmModel, diags *diag.Diagnostics) {
+    if m.Security == nil || generation(m) != 1 { return }
+    if m.Security.TPM.ValueBool() {
+        diags.AddAttributeError(path.Root("security").AtName("tpm"), "tpm not supported", "A virtual TPM needs a Generation 2 VM; set generation = 2.")
+    }
+    if m.Security.Encrypt.ValueBool() {
+        diags.AddAttributeError(path.Root("security").AtName("encrypt"), "encryption not supported", "Encryption support needs a Generation 2 VM; set generation = 2.")
+    }
+}
+
+// securityChanges returns the security settings that differ from prior (nil on create). A
+// removed block leaves the host as it is.
+func securityChanges(m, prior *vmModel) (tpm, encrypt *bool) {
+    if m.Security == nil { return nil, nil }
+    var was *securityModel
+    if prior != nil { was = prior.Security }
+    if v := m.Security.TPM; !v.IsNull() && !v.IsUnknown() && (was == nil || !v.Equal(was.TPM)) { b := v.ValueBool(); tpm = &b }
+    if v := m.Security.Encrypt; !v.IsNull() && !v.IsUnknown() && (was == nil || !v.Equal(was.Encrypt)) { b := v.ValueBool(); encrypt = &b }
+    return tpm, encrypt
+}
+
+// applySecurity sets up a key protector when a feature needs one, then sets the vTPM and
+// encryption support. The caller has the VM off. Host refusals name the setting and why.
+func (r *VMResource) applySecurity(ctx context.Context, name string, tpm, encrypt *bool, diags *diag.Diagnostics) {
+    if tpm == nil && encrypt == nil { return }
+    at := path.Root("security")
+    if (tpm != nil && *tpm) || (encrypt != nil && *encrypt) {
+        if err := r.cl.EnsureKeyProtector(ctx, name); err != nil {
+            securityError(diags, at, "key protector", err)
+            return
+        }
+    }
+    if tpm != nil {
+        if err := r.cl.SetTPM(ctx, name, *tpm); err != nil {
+            securityError(diags, at.AtName("tpm"), "tpm", err)
+            return
+        }
+    }
+    if encrypt != nil {
+        if err := r.cl.SetEncryptionSupport(ctx, name, *encrypt); err != nil {
+            securityError(diags, at.AtName("encrypt"), "encryption support", err)
+            return
+        }
+    }
+}
+
+func securityError(diags *diag.Diagnostics, at path.Path, what string, err error) {
+    switch {
+    case client.IsUnsupported(err):
+        diags.AddAttributeError(at, what+" not supported", "The host cannot change "+what+" on this VM. A vTPM and encryption support need a Generation 2 VM and a host that can issue a local key protector (or a configured Host Guardian Service).\n\n"+client.Detail(err))
+    case client.IsConflict(err):
+        diags.AddAttributeError(at, what+" change refused", "The host refused to change "+what+" in the VM's current state; it must be powered off.\n\n"+client.Detail(err))
+    default:
+        diags.AddAttributeError(at, what+" failed", client.Detail(err))
+    }
+}
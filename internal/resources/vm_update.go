@@ -0,0 +1,228 @@
+package resources
+
+import (
+    "context"
+    "errors"
+    "strconv"
+    "strings"
+    "time"
+
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+
+    "github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+)
+
+func (r *VMResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+    var plan vmModel
+    var state vmModel
+    resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+    resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+    if resp.Diagnostics.HasError() { return }
+    if r.cl == nil {
+        resp.Diagnostics.AddError("provider not configured", "client missing")
+        return
+    }
+    if plan.ID.IsNull() || plan.ID.IsUnknown() || plan.ID.ValueString() == "" {
+        plan.ID = state.ID
+    }
+    limit, d := plan.Timeouts.Update(ctx, defaultUpdateTimeout)
+    resp.Diagnostics.Append(d...)
+    if resp.Diagnostics.HasError() { return }
+    ctx, cancel, op := withDeadline(ctx, "update", limit)
+    defer cancel()
+    defer op.report(ctx, &resp.Diagnostics)
+
+    op.at("reading the VM")
+    vm, err := r.cl.GetVm(ctx, state.Name.ValueString())
+    if err != nil {
+        resp.Diagnostics.AddError("update failed", client.Detail(err))
+        return
+    }
+    owned := readOwnership(ctx, req.Private, &resp.Diagnostics)
+    off := &offlineSession{r: r, m: &plan, wasRunning: vm.Running()}
+    op.at("resizing cpu and memory")
+    r.resize(ctx, off, &plan, &state, &resp.Diagnostics)
+    if !resp.Diagnostics.HasError() {
+        op.at("reconciling disks")
+        r.reconcileDisks(ctx, off, &plan, &state, owned, &resp.Diagnostics)
+    }
+    // Save what was created or deleted even when a later step fails
+    if owned.recorded { writeOwnership(ctx, resp.Private, owned, &resp.Diagnostics) }
+    if !resp.Diagnostics.HasError() {
+        op.at("reconciling network adapters")
+        r.reconcileNics(ctx, off, &plan, &state, &resp.Diagnostics)
+    }
+    // Firmware goes after disks and adapters so boot_order can refer to new ones
+    if !resp.Diagnostics.HasError() {
+        op.at("configuring firmware")
+        r.applyFirmware(ctx, off, &plan, &state, &resp.Diagnostics)
+    }
+    if tpm, encrypt := securityChanges(&plan, &state); !resp.Diagnostics.HasError() && (tpm != nil || encrypt != nil) {
+        if err := off.stop(ctx); err != nil {
+            resp.Diagnostics.AddError("stop for security change failed", client.Detail(err))
+        } else {
+            op.at("configuring security")
+            r.applySecurity(ctx, state.Name.ValueString(), tpm, encrypt, &resp.Diagnostics)
+        }
+    }
+    // Restore the original power state however the changes went, even past the deadline
+    rctx, rcancel := cleanupContext(ctx, powerTimeout(&plan)+30*time.Second)
+    off.restore(rctx, &resp.Diagnostics)
+    rcancel()
+    if resp.Diagnostics.HasError() { return }
+
+    // Power transitions if changed
+    if !plan.Power.IsNull() && state.Name.ValueString() != "" {
+        op.at("waiting for power state " + plan.Power.ValueString())
+        if err := r.applyDesiredPower(ctx, &plan); err != nil {
+            // Store the power state the VM is actually in, not the one that was asked for
+            plan.Power = state.Power
+            r.recordPower(ctx, &plan)
+            resp.Diagnostics.AddAttributeError(path.Root("power"), "power change failed", client.Detail(err))
+        }
+    }
+    settleUnknowns(&plan)
+    resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+// offlineSession stops the VM at most once for changes the host cannot make online, and restarts
+// it afterwards if it was running.
+type offlineSession struct {
+    r          *VMResource
+    m          *vmModel
+    wasRunning bool
+    stopped    bool
+}
+
+// stop powers the VM off (per stop_method) unless it already is.
+func (s *offlineSession) stop(ctx context.Context) error {
+    if !s.wasRunning || s.stopped { return nil }
+    if err := s.r.stopVm(ctx, s.m); err != nil { return err }
+    s.stopped = true
+    return nil
+}
+
+// restore starts the VM again if stop powered it off, unless the plan stops it anyway.
+func (s *offlineSession) restore(ctx context.Context, diags *diag.Diagnostics) {
+    if !s.stopped || strings.EqualFold(s.m.Power.ValueString(), "stopped") { return }
+    name := s.m.Name.ValueString()
+    if err := s.r.cl.StartVm(ctx, name); err != nil {
+        diags.AddError("restart after update failed", "The VM was stopped to apply the change and could not be started again: "+client.Detail(err))
+        return
+    }
+    if err := s.r.waitForPower(ctx, name, "running", powerTimeout(s.m)); err != nil {
+        diags.AddError("restart after update failed", "The VM was stopped to apply the change and did not come back up: "+client.Detail(err))
+    }
+}
+
+// resize applies cpu and memory changes in place. The VM is stopped only when the host cannot
+// apply the change online.
+func (r *VMResource) resize(ctx context.Context, off *offlineSession, plan, state *vmModel, diags *diag.Diagnostics) {
+    name := state.Name.ValueString()
+    var cpu, mem *int
+    if !plan.CPU.IsNull() && !plan.CPU.IsUnknown() && !plan.CPU.Equal(state.CPU) {
+        c := int(plan.CPU.ValueInt64()); cpu = &c
+    }
+    if pm, ok := toMB(plan.Memory.ValueString()); ok && !plan.Memory.IsUnknown() {
+        if sm, ok := toMB(state.Memory.ValueString()); !ok || sm != pm { mem = &pm }
+    }
+    dyn := dynamicChanged(plan, state)
+    if cpu == nil && mem == nil && !dyn { return }
+
+    if cpu != nil {
+        // Hyper-V has no vCPU hot-add: the VM must be off
+        if err := off.stop(ctx); err != nil {
+            diags.AddError("stop for cpu change failed", client.Detail(err))
+            return
+        }
+        if err := r.cl.SetVmProcessorCount(ctx, name, *cpu); err != nil {
+            diags.AddAttributeError(path.Root("cpu"), "cpu update failed", client.Detail(err))
+            return
+        }
+    }
+    if mem != nil || dyn {
+        req := client.SetVmMemoryRequest{StartupMB: mem}
+        if dyn {
+            dynamicRequest(plan.DynamicMemory, &req)
+            // Switching dynamic memory on or off is refused while the VM runs
+            if dynamicToggled(plan, state) {
+                if err := off.stop(ctx); err != nil {
+                    diags.AddError("stop for memory change failed", client.Detail(err))
+                    return
+                }
+            }
+        }
+        err := r.cl.SetVmMemory(ctx, name, req)
+        if err != nil && off.wasRunning && !off.stopped && !client.IsNotFound(err) && !client.IsPolicyDenied(err) && !client.IsUnauthorized(err) {
+            // Runtime resize refused (older host, or dynamic memory enabled): retry with the VM off
+            tflog.Info(ctx, "memory resize needs the VM off", map[string]any{"vm": name, "error": err.Error()})
+            if serr := off.stop(ctx); serr != nil {
+                diags.AddError("stop for memory change failed", client.Detail(serr))
+                return
+            }
+            err = r.cl.SetVmMemory(ctx, name, req)
+        }
+        if err != nil {
+            at := path.Root("memory")
+            if mem == nil { at = path.Root("dynamic_memory") }
+            diags.AddAttributeError(at, "memory update failed", client.Detail(err))
+            return
+        }
+    }
+    if err := r.waitForConfig(ctx, name, cpu, mem, verifyWindow(plan)); err != nil {
+        diags.AddError("vm update not applied", err.Error())
+    }
+}
+
+// powerTimeout returns wait_timeout_seconds, default 240.
+func powerTimeout(m *vmModel) time.Duration {
+    if !m.WaitTimeoutSec.IsNull() && m.WaitTimeoutSec.ValueInt64() > 0 { return time.Duration(m.WaitTimeoutSec.ValueInt64()) * time.Second }
+    return 240 * time.Second
+}
+
+// shutdownTimeout returns shutdown_timeout_seconds, default 60.
+func shutdownTimeout(m *vmModel) time.Duration {
+    if !m.ShutdownTimeoutSec.IsNull() && m.ShutdownTimeoutSec.ValueInt64() > 0 { return time.Duration(m.ShutdownTimeoutSec.ValueInt64()) * time.Second }
+    return 60 * time.Second
+}
+
+// verifyWindow bounds how long cpu/memory read-back waits: wait_timeout_seconds, default 20s. The
+// operation deadline from timeouts still applies.
+func verifyWindow(m *vmModel) time.Duration {
+    sec := 20
+    if !m.WaitTimeoutSec.IsNull() && m.WaitTimeoutSec.ValueInt64() > 0 { sec = int(m.WaitTimeoutSec.ValueInt64()) }
+    return time.Duration(sec) * time.Second
+}
+
+// waitForConfig polls processor and memory config until they match cpu and mem (nil skips a
+// check). The host applies settings asynchronously; any mismatch left after window is returned.
+func (r *VMResource) waitForConfig(ctx context.Context, name string, cpu, mem *int, window time.Duration) error {
+    deadline := time.Now().Add(window)
+    for {
+        var mismatch []string
+        if cpu != nil {
+            if pc, err := r.cl.GetVmProcessorConfig(ctx, name); err != nil {
+                mismatch = append(mismatch, "processor config: "+client.Detail(err))
+            } else if pc.Count != *cpu {
+                mismatch = append(mismatch, "Server reports CPU count="+strconv.Itoa(pc.Count)+", expected="+strconv.Itoa(*cpu))
+            }
+        }
+        if mem != nil {
+            if mc, err := r.cl.GetVmMemoryConfig(ctx, name); err != nil {
+                mismatch = append(mismatch, "memory config: "+client.Detail(err))
+            } else if mc.StartupMB != *mem {
+                mismatch = append(mismatch, "Server reports startupMB="+strconv.Itoa(mc.StartupMB)+", expected="+strconv.Itoa(*mem))
+            }
+        }
+        if len(mismatch) == 0 { return nil }
+        if time.Now().After(deadline) { return errors.New(strings.Join(mismatch, "; ")) }
+        select {
+        case <-ctx.Done():
+            return ctx.Err()
+        case <-time.After(time.Second):
+        }
+    }
+}
@@ -2,14 +2,17 @@ package sources
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	"github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+	"github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/policy"
 )
 
 var _ datasource.DataSource = &DiskPlanDataSource{}
@@ -99,11 +102,17 @@ func (d *DiskPlanDataSource) Read(ctx context.Context, req datasource.ReadReques
 	if !data.Ext.IsNull() { s := data.Ext.ValueString(); in.Ext = &s }
 
 	out, err := cl.PlanDisk(ctx, in)
+	fallback := false
+	if err != nil && (client.IsPolicyDenied(err) || client.IsUnauthorized(err)) {
+		// A definitive denial must not be papered over by the client-side fallback
+		resp.Diagnostics.AddError("plan-disk failed", client.Detail(err))
+		return
+	}
 	if err != nil {
 		// Fallback: client-side suggestion using effective policy roots
 		pol, perr := cl.Policy(ctx)
 		if perr != nil {
-			resp.Diagnostics.AddError("plan-disk failed", err.Error())
+			resp.Diagnostics.AddError("plan-disk failed", client.Detail(err))
 			return
 		}
 		// Prefer requested root if present; else first root
@@ -121,6 +130,7 @@ func (d *DiskPlanDataSource) Read(ctx context.Context, req datasource.ReadReques
 		if !data.Ext.IsNull() && data.Ext.ValueString() != "" { ext = data.Ext.ValueString() }
 		p := filepath.Join(root, data.VMName.ValueString()+"."+ext)
 		// Synthesize a minimal response compatible with the schema
+		fallback = true
 		out = &client.DiskPlanResponse{
 			Path:           p,
 			Reason:         "fallback:client:first_allowed_root",
@@ -132,6 +142,20 @@ func (d *DiskPlanDataSource) Read(ctx context.Context, req datasource.ReadReques
 			Warnings:       []string{"server:plan-disk unavailable; used client fallback"},
 		}
 	}
+	if fallback {
+		policy.Notice(&resp.Diagnostics, cl.Strict(), path.Empty(), "plan-disk fallback", "Server plan-disk unavailable ("+client.Detail(err)+"); suggested "+out.Path+" from the effective policy roots without a free-space check.")
+	} else {
+		for _, w := range out.Warnings { policy.Notice(&resp.Diagnostics, cl.Strict(), path.Empty(), "plan-disk warning", w) }
+		if !out.Writable {
+			policy.Notice(&resp.Diagnostics, cl.Strict(), path.Empty(), "plan-disk path not writable", out.Path+" is not writable by the API host.")
+		}
+		if !data.MinFreeGB.IsNull() && int64(out.FreeGBAfter) < data.MinFreeGB.ValueInt64() {
+			policy.Notice(&resp.Diagnostics, cl.Strict(), path.Empty(), "low free space", fmt.Sprintf("%s would leave %d GB free on %s (min_free_gb = %d).", out.Path, out.FreeGBAfter, out.MatchedRoot, data.MinFreeGB.ValueInt64()))
+		}
+	}
+	if resp.Diagnostics.HasError() {
+		return
+	}
 	data.ID = types.StringValue(data.VMName.ValueString() + ":" + data.Purpose.ValueString())
 	data.Path = types.StringValue(out.Path)
 	data.Reason = types.StringValue(out.Reason)
@@ -2,12 +2,15 @@ package sources
 
 import (
 	"context"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-framework/datasource"
 	"github.com/hashicorp/terraform-plugin-framework/datasource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/path"
 	"github.com/hashicorp/terraform-plugin-framework/types"
 
 	"github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/client"
+	"github.com/vinitsiriya/hyperv-management-api/terraform-provider-hypervapi-v2/internal/policy"
 )
 
 var _ datasource.DataSource = &PathValidateDataSource{}
@@ -68,7 +71,15 @@ func (d *PathValidateDataSource) Read(ctx context.Context, req datasource.ReadRe
 	in := client.PathValidateRequest{Path: data.Path.ValueString(), Operation: data.Operation.ValueString(), Ext: data.Ext.ValueString()}
 	out, err := cl.ValidatePath(ctx, in)
 	if err != nil {
-		resp.Diagnostics.AddError("validate-path failed", err.Error())
+		resp.Diagnostics.AddError("validate-path failed", client.Detail(err))
+		return
+	}
+	// Outside strict mode a disallowed path is just the allowed/violations result
+	if cl.Strict() && (!out.Allowed || len(out.Violations) > 0) {
+		msg := data.Path.ValueString() + " is not allowed for " + data.Operation.ValueString()
+		if out.Message != "" { msg += ": " + out.Message }
+		if len(out.Violations) > 0 { msg += "\nViolations: " + strings.Join(out.Violations, "; ") }
+		policy.Violation(&resp.Diagnostics, path.Empty(), "path violates policy", msg)
 		return
 	}
 	data.ID = types.StringValue(data.Path.ValueString())
@@ -52,7 +52,7 @@ func (d *PolicyDataSource) Read(ctx context.Context, req datasource.ReadRequest,
 	var data policyModel
 	out, err := cl.Policy(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError("policy fetch failed", err.Error())
+		resp.Diagnostics.AddError("policy fetch failed", client.Detail(err))
 		return
 	}
 	data.ID = types.StringValue("policy")
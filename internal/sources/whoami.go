@@ -54,7 +54,7 @@ func (d *WhoAmIDataSource) Read(ctx context.Context, req datasource.ReadRequest,
 	var data whoamiModel
 	out, err := cl.WhoAmI(ctx)
 	if err != nil {
-		resp.Diagnostics.AddError("whoami failed", err.Error())
+		resp.Diagnostics.AddError("whoami failed", client.Detail(err))
 		return
 	}
 	data.ID = types.StringValue(out.User)